@@ -0,0 +1,106 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"tkestack.io/cron-hpa/pkg/admission"
+	"tkestack.io/cron-hpa/pkg/cronhpa"
+	"tkestack.io/cron-hpa/pkg/pause"
+	"tkestack.io/cron-hpa/pkg/sharding"
+
+	"k8s.io/apiserver/pkg/server/healthz"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/klog"
+)
+
+// healthzHandler serves /healthz, /readyz and the optional /debug/schedule.
+// /healthz reports whether the process should be restarted, and /readyz reports
+// whether it is ready to serve.
+func healthzHandler(controller *cronhpa.Controller, admissionServer *admission.Server,
+	electionChecker *leaderelection.HealthzAdaptor, sharder *sharding.Sharder, pauseSwitch *pause.Switch) http.Handler {
+	mux := http.NewServeMux()
+
+	healthzChecks := []healthz.HealthzChecker{
+		healthz.PingHealthz,
+		healthz.NamedCheck("sync-loop", func(_ *http.Request) error {
+			return controller.CheckSync()
+		}),
+	}
+	if electionChecker != nil {
+		healthzChecks = append(healthzChecks, electionChecker)
+	}
+	if sharder != nil {
+		healthzChecks = append(healthzChecks, healthz.NamedCheck("sharding", func(_ *http.Request) error {
+			return sharder.Check()
+		}))
+	}
+	if admissionServer != nil {
+		healthzChecks = append(healthzChecks, healthz.NamedCheck("admission-server", func(_ *http.Request) error {
+			return admissionServer.Check()
+		}))
+	}
+	healthz.InstallHandler(mux, healthzChecks...)
+
+	readyzChecks := []healthz.HealthzChecker{
+		healthz.NamedCheck("informer-sync", func(_ *http.Request) error {
+			if !controller.HasSynced() {
+				return fmt.Errorf("informers have not synced yet")
+			}
+			return nil
+		}),
+	}
+	if admissionServer != nil {
+		readyzChecks = append(readyzChecks, healthz.NamedCheck("webhook-certificate", func(_ *http.Request) error {
+			return admissionServer.CheckCertificate()
+		}))
+	}
+	readyzMux := http.NewServeMux()
+	healthz.InstallPathHandler(readyzMux, "/readyz", readyzChecks...)
+	mux.Handle("/readyz/", readyzMux)
+	// The pause doesn't fail /readyz, which would take the admission webhook
+	// down while on-call may need it, but its reason is appended to the body.
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		readyzMux.ServeHTTP(w, r)
+		if paused, reason := pauseSwitch.Paused(); paused {
+			fmt.Fprintf(w, "\npaused: %s\n", reason)
+		}
+	})
+	// The pause is reported on its own by /readyz/paused, which fails while paused.
+	mux.HandleFunc("/readyz/paused", func(w http.ResponseWriter, _ *http.Request) {
+		if paused, reason := pauseSwitch.Paused(); paused {
+			http.Error(w, "paused: "+reason, http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+
+	if enableDebugSchedule {
+		mux.HandleFunc("/debug/schedule", func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(controller.UpcomingActions()); err != nil {
+				klog.Errorf("Failed to write schedule: %v", err)
+			}
+		})
+	}
+
+	return mux
+}
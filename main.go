@@ -20,24 +20,42 @@ package main
 import (
 	"context"
 	"flag"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
 	"tkestack.io/cron-hpa/pkg/admission"
+	"tkestack.io/cron-hpa/pkg/api"
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
 	clientset "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+	informers "tkestack.io/cron-hpa/pkg/client/informers/externalversions"
+	cronhpainformers "tkestack.io/cron-hpa/pkg/client/informers/externalversions/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/cloudevents"
+	"tkestack.io/cron-hpa/pkg/conflict"
 	"tkestack.io/cron-hpa/pkg/cronhpa"
 	"tkestack.io/cron-hpa/pkg/logs"
+	"tkestack.io/cron-hpa/pkg/metrics"
+	"tkestack.io/cron-hpa/pkg/notify"
+	"tkestack.io/cron-hpa/pkg/pause"
+	"tkestack.io/cron-hpa/pkg/resourcelock"
+	"tkestack.io/cron-hpa/pkg/sharding"
 
 	"github.com/spf13/pflag"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 	apiserverconfig "k8s.io/apiserver/pkg/apis/config"
+	kubeinformers "k8s.io/client-go/informers"
+	autoscalinginformers "k8s.io/client-go/informers/autoscaling/v1"
 	"k8s.io/client-go/kubernetes"
 	restclient "k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/tools/leaderelection"
-	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
 	"k8s.io/klog"
 	"k8s.io/kubernetes/pkg/client/leaderelectionconfig"
 	controllerpkg "k8s.io/kubernetes/pkg/controller"
@@ -47,6 +65,11 @@ const (
 	DefaultLeaseDuration = 15 * time.Second
 	DefaultRenewDeadline = 10 * time.Second
 	DefaultRetryPeriod   = 2 * time.Second
+	// DefaultLeaderElectionTimeout is how long after the lease expires the leader
+	// may fail to renew it before it is considered unhealthy.
+	DefaultLeaderElectionTimeout = 20 * time.Second
+	// DefaultShutdownTimeout is how long HTTP servers wait for active requests on shutdown.
+	DefaultShutdownTimeout = 10 * time.Second
 )
 
 var (
@@ -63,8 +86,34 @@ var (
 		LeaseDuration: metav1.Duration{Duration: DefaultLeaseDuration},
 		RenewDeadline: metav1.Duration{Duration: DefaultRenewDeadline},
 		RetryPeriod:   metav1.Duration{Duration: DefaultRetryPeriod},
-		ResourceLock:  resourcelock.EndpointsResourceLock,
+		ResourceLock:  rl.EndpointsResourceLock,
 	}
+	// leaderElectResourceNamespace is the namespace of the leader lock, defaults to namespace.
+	leaderElectResourceNamespace string
+	// leaderElectResourceName is the name of the leader lock.
+	leaderElectResourceName string
+	// enableSharding shares cronhpas among all replicas instead of electing a leader.
+	enableSharding bool
+	// concurrentSyncs is the number of cronhpas synced concurrently.
+	concurrentSyncs int
+	// watchNamespaces are the namespaces to watch cronhpas in, empty for all namespaces.
+	watchNamespaces []string
+	// cronhpaSelector is the label selector of watched cronhpas.
+	cronhpaSelector string
+	// scaleRateLimit limits scale operations globally and per namespace.
+	scaleRateLimit cronhpa.ScaleRateLimit
+	// controllerName is the name of this controller, which only acts on cronhpas assigned to it.
+	controllerName string
+	// dumpRBAC prints RBAC manifests matching the flags and exits.
+	dumpRBAC bool
+	// notificationConfig is the file of notification sinks.
+	notificationConfig string
+	// cloudEventsSink is the URL to send CloudEvents to, empty to disable.
+	cloudEventsSink string
+	// cloudEventsMode is the HTTP content mode of CloudEvents.
+	cloudEventsMode string
+	// cloudEventsQueueSize is the max number of pending CloudEvents.
+	cloudEventsQueueSize int
 
 	// Admission related config
 	registerAdmission bool
@@ -72,18 +121,82 @@ var (
 	tlsCertFile       string
 	tlsKeyFile        string
 	listenAddress     string
+	// conflictPolicy is how the admission webhook handles conflicting CronHPAs.
+	conflictPolicy string
 	// namespace to deploy CronHPA controller
 	namespace string
+
+	// metricsAddress is the address to serve prometheus metrics on.
+	metricsAddress string
+	// healthAddress is the address to serve health and debug endpoints on.
+	healthAddress string
+	// enableDebugSchedule enables the endpoint dumping upcoming actions.
+	enableDebugSchedule bool
+	// pauseConfigMap is the name of the ConfigMap in namespace pausing all
+	// scaling, empty to disable.
+	pauseConfigMap string
+	// pauseFile is the file pausing all scaling while it exists, empty to disable.
+	pauseFile string
+
+	// apiAddress is the address to serve the API on, empty to disable.
+	apiAddress string
+	// apiTokenSecret is the namespace/name of the Secret of the API token.
+	apiTokenSecret string
+	// apiTokenReview authenticates API tokens by TokenReviews.
+	apiTokenReview bool
+	// apiTLSCertFile and apiTLSKeyFile serve the API over HTTPS if set.
+	apiTLSCertFile string
+	apiTLSKeyFile  string
 )
 
 func main() {
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 	addFlags(pflag.CommandLine)
 	pflag.Parse()
+	if enableSharding && leaderElection.LeaderElect {
+		klog.Fatalf("--sharding and --leader-elect are mutually exclusive")
+	}
+	if err := resourcelock.ValidateLockType(leaderElection.ResourceLock); err != nil {
+		klog.Fatalf("Invalid --leader-elect-resource-lock: %v", err)
+	}
+	if (scaleRateLimit.QPS > 0 && scaleRateLimit.Burst < 1) || (scaleRateLimit.NamespaceQPS > 0 && scaleRateLimit.NamespaceBurst < 1) {
+		klog.Fatalf("--scale-burst and --namespace-scale-burst must be positive with their QPS")
+	}
+	if policy := conflict.Policy(conflictPolicy); policy != conflict.Warn && policy != conflict.Reject {
+		klog.Fatalf("--conflict-policy must be %s or %s", conflict.Warn, conflict.Reject)
+	}
+	selector, err := labels.Parse(cronhpaSelector)
+	if err != nil {
+		klog.Fatalf("Invalid --cronhpa-selector: %v", err)
+	}
+	if dumpRBAC {
+		if err := writeRBAC(os.Stdout); err != nil {
+			klog.Fatalf("Failed to dump RBAC manifests: %v", err)
+		}
+		return
+	}
 
 	logs.InitLogs()
 	defer logs.FlushLogs()
 
+	// ctx is cancelled on SIGTERM or SIGINT to shut down gracefully.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go handleSignals(cancel)
+
+	// HTTP servers are stopped only after in-flight syncs are drained.
+	serversCtx, stopServers := context.WithCancel(context.Background())
+	var servers wait.Group
+	defer servers.Wait()
+	defer stopServers()
+
+	if metricsAddress != "" {
+		metrics.Register()
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		servers.Start(func() { serveHTTP(serversCtx, "metrics", metricsAddress, mux, "", "") })
+	}
+
 	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
 	if err != nil {
 		klog.Fatalf("Error building kubeconfig: %s", err.Error())
@@ -110,11 +223,157 @@ func main() {
 		ClientConfig: cfg,
 	}
 
-	controller, err := cronhpa.NewController(kubeClient, cronhpaClient, rootClientBuilder)
+	lockNamespace := leaderElectResourceNamespace
+	if lockNamespace == "" {
+		lockNamespace = namespace
+	}
+	id, err := resourcelock.NewIdentity()
+	if err != nil {
+		klog.Fatalf("Failed to get hostname: %s", err.Error())
+	}
+
+	var sharder *sharding.Sharder
+	if enableSharding {
+		shardingClient := kubernetes.NewForConfigOrDie(restclient.AddUserAgent(cfg, "cron-hpa-sharding"))
+		sharder, err = sharding.NewSharder(shardingClient.CoordinationV1(), sharding.Config{
+			Namespace:     lockNamespace,
+			Group:         leaderElectResourceName,
+			Identity:      id,
+			LeaseDuration: leaderElection.LeaseDuration.Duration,
+			RenewDeadline: leaderElection.RenewDeadline.Duration,
+			RetryPeriod:   leaderElection.RetryPeriod.Duration,
+		})
+		if err != nil {
+			klog.Fatalf("Error creating sharder: %v", err)
+		}
+	}
+
+	// One informer factory per watched namespace, so that no cluster-wide
+	// permission is needed.
+	informerNamespaces := watchNamespaces
+	if len(informerNamespaces) == 0 {
+		informerNamespaces = []string{metav1.NamespaceAll}
+	}
+	var cronhpaInformerFactories []informers.SharedInformerFactory
+	var cronhpaInformers []cronhpainformers.CronHPAInformer
+	var hpaInformerFactories []kubeinformers.SharedInformerFactory
+	var hpaInformers []autoscalinginformers.HorizontalPodAutoscalerInformer
+	for _, ns := range informerNamespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(cronhpaClient, 0,
+			informers.WithNamespace(ns),
+			informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+				options.LabelSelector = selector.String()
+			}))
+		cronhpaInformerFactories = append(cronhpaInformerFactories, factory)
+		cronhpaInformers = append(cronhpaInformers, factory.Cronhpacontroller().V1().CronHPAs())
+		hpaFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, 0, kubeinformers.WithNamespace(ns))
+		hpaInformerFactories = append(hpaInformerFactories, hpaFactory)
+		hpaInformers = append(hpaInformers, hpaFactory.Autoscaling().V1().HorizontalPodAutoscalers())
+	}
+	conflictIndex, err := conflict.NewIndex(cronhpaInformers, hpaInformers)
+	if err != nil {
+		klog.Fatalf("Error indexing scale targets: %v", err)
+	}
+	// ScalingFreezes are cluster-scoped, so they are only watched if all
+	// namespaces are, and a namespace-scoped install needs no cluster-wide
+	// permission.
+	var freezeInformer cronhpainformers.ScalingFreezeInformer
+	if len(watchNamespaces) == 0 {
+		freezeInformerFactory := informers.NewSharedInformerFactory(cronhpaClient, 0)
+		cronhpaInformerFactories = append(cronhpaInformerFactories, freezeInformerFactory)
+		freezeInformer = freezeInformerFactory.Cronhpacontroller().V1().ScalingFreezes()
+	}
+	pauseSwitch, pauseInformerFactory := pause.New(kubeClient, namespace, pauseConfigMap, pauseFile)
+
+	var notifiers notify.Notifiers
+	if notificationConfig != "" {
+		config, err := notify.LoadConfig(notificationConfig)
+		if err != nil {
+			klog.Fatalf("Error loading notification config: %v", err)
+		}
+		dispatcher, err := notify.NewDispatcher(config)
+		if err != nil {
+			klog.Fatalf("Error creating notification dispatcher: %v", err)
+		}
+		// Stopped with HTTP servers, so that notifications of in-flight syncs are sent.
+		servers.Start(func() { dispatcher.Run(serversCtx.Done()) })
+		notifiers = append(notifiers, dispatcher)
+	}
+	if cloudEventsSink != "" {
+		emitter, err := cloudevents.NewEmitter(cloudEventsSink, cloudevents.Mode(cloudEventsMode), cloudEventsQueueSize)
+		if err != nil {
+			klog.Fatalf("Error creating CloudEvents emitter: %v", err)
+		}
+		servers.Start(func() { emitter.Run(serversCtx.Done()) })
+		notifiers = append(notifiers, emitter)
+	}
+	var notifier notify.Notifier
+	if len(notifiers) > 0 {
+		notifier = notifiers
+	}
+
+	controller, err := cronhpa.NewController(kubeClient, cronhpaClient, cronhpaInformers, freezeInformer, conflictIndex, rootClientBuilder,
+		controllerName, sharder, scaleRateLimit, pauseSwitch, namespace, notifier)
 	if err != nil {
 		klog.Fatalf("Failed to new controller: %s", err)
 	}
 
+	// Admission server is stateless, so it serves on all replicas.
+	var admissionServer *admission.Server
+	if registerAdmission {
+		if len(watchNamespaces) > 0 {
+			if err := admission.CheckScopedSupport(kubeClient); err != nil {
+				klog.Fatalf("--register-admission with --watch-namespaces is not supported: %v", err)
+			}
+		}
+		admissionServer, err = admission.NewServer(listenAddress, tlsCertFile, tlsKeyFile, watchNamespaces, selector, kubeClient, cronhpaClient, namespace,
+			conflictIndex, conflict.Policy(conflictPolicy))
+		if err != nil {
+			klog.Fatalf("Error new admission server: %v", err)
+		}
+		servers.Start(func() { admissionServer.Run(serversCtx) })
+	}
+
+	// The API only sets annotations, so it serves on all replicas.
+	if apiAddress != "" {
+		var authenticators []api.Authenticator
+		if apiTokenSecret != "" {
+			ns, name, err := splitAPITokenSecret()
+			if err != nil {
+				klog.Fatal(err)
+			}
+			authenticators = append(authenticators, api.NewSecretTokenAuthenticator(kubeClient, ns, name))
+		}
+		if apiTokenReview {
+			authenticators = append(authenticators, api.NewTokenReviewAuthenticator(kubeClient, cronhpaClient))
+		}
+		if len(authenticators) == 0 {
+			klog.Fatalf("--api-address requires --api-token-secret or --api-token-review")
+		}
+		handler := api.NewHandler(cronhpaClient, authenticators)
+		servers.Start(func() { serveHTTP(serversCtx, "api", apiAddress, handler, apiTLSCertFile, apiTLSKeyFile) })
+	}
+
+	var electionChecker *leaderelection.HealthzAdaptor
+	if leaderElection.LeaderElect {
+		electionChecker = leaderelection.NewLeaderHealthzAdaptor(DefaultLeaderElectionTimeout)
+	}
+	if healthAddress != "" {
+		handler := healthzHandler(controller, admissionServer, electionChecker, sharder, pauseSwitch)
+		servers.Start(func() { serveHTTP(serversCtx, "health", healthAddress, handler, "", "") })
+	}
+
+	// Informers are started on all replicas, so standby replicas are ready to take over.
+	for _, factory := range cronhpaInformerFactories {
+		factory.Start(ctx.Done())
+	}
+	for _, factory := range hpaInformerFactories {
+		factory.Start(ctx.Done())
+	}
+	if pauseInformerFactory != nil {
+		pauseInformerFactory.Start(ctx.Done())
+	}
+
 	run := func(ctx context.Context) {
 		if createCRD {
 			wait.PollUntil(time.Second*5, func() (bool, error) { return cronhpa.EnsureCRDCreated(extensionsClient) }, ctx.Done())
@@ -122,36 +381,40 @@ func main() {
 
 		if registerAdmission {
 			wait.PollImmediateUntil(time.Second*5, func() (bool, error) {
-				return admission.Register(kubeClient, namespace, tlsCAfile)
+				return admission.Register(kubeClient, namespace, tlsCAfile, watchNamespaces)
 			}, ctx.Done())
-			server, err := admission.NewServer(listenAddress, tlsCertFile, tlsKeyFile)
-			if err != nil {
-				klog.Fatalf("Error new admission server: %v", err)
-			}
-			go server.Run(ctx.Done())
 		}
 
-		if err = controller.Run(ctx.Done()); err != nil {
-			klog.Fatalf("Error running controller: %s", err.Error())
+		if err := controller.Run(ctx, concurrentSyncs); err != nil {
+			klog.Errorf("Error running controller: %s", err.Error())
 		}
 	}
 
-	if !leaderElection.LeaderElect {
-		run(context.Background())
-		panic("unreachable")
+	if sharder != nil {
+		// Leave the group only after in-flight syncs are drained, so that no
+		// other replica acts on our cronhpas meanwhile.
+		stopSharder := make(chan struct{})
+		var sharderDone wait.Group
+		sharderDone.Start(func() { sharder.Run(stopSharder) })
+		run(ctx)
+		close(stopSharder)
+		sharderDone.Wait()
+		klog.Info("Controller stopped")
+		return
 	}
 
-	id, err := os.Hostname()
-	if err != nil {
-		klog.Fatalf("Failed to get hostname: %s", err.Error())
+	if !leaderElection.LeaderElect {
+		run(ctx)
+		klog.Info("Controller stopped")
+		return
 	}
 
 	leaderElectionClient := kubernetes.NewForConfigOrDie(restclient.AddUserAgent(cfg, "cron-hpa-leader-election"))
-	rl, err := resourcelock.New(leaderElection.ResourceLock,
-		"kube-system",
-		"cron-hpa-controller",
-		leaderElectionClient.CoreV1(),
-		resourcelock.ResourceLockConfig{
+	lock, err := resourcelock.New(leaderElection.ResourceLock,
+		lockNamespace,
+		leaderElectResourceName,
+		leaderElectionClient,
+		rl.ResourceLockConfig{
 			Identity:      id,
 			EventRecorder: controller.GetEventRecorder(),
 		})
@@ -159,19 +422,96 @@ func main() {
 		klog.Fatalf("error creating lock: %v", err)
 	}
 
-	leaderelection.RunOrDie(context.Background(), leaderelection.LeaderElectionConfig{
-		Lock:          rl,
+	// runOnce makes sure run is either waited for or never started once
+	// leader election returns, as OnStartedLeading is called asynchronously.
+	var runOnce sync.Once
+	le, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
 		LeaseDuration: leaderElection.LeaseDuration.Duration,
 		RenewDeadline: leaderElection.RenewDeadline.Duration,
 		RetryPeriod:   leaderElection.RetryPeriod.Duration,
+		WatchDog:      electionChecker,
 		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: run,
+			OnStartedLeading: func(ctx context.Context) {
+				runOnce.Do(func() { run(ctx) })
+			},
 			OnStoppedLeading: func() {
-				klog.Fatalf("leaderelection lost")
+				klog.Info("Stopped leading")
 			},
 		},
 	})
-	panic("unreachable")
+	if err != nil {
+		klog.Fatalf("error creating leader elector: %v", err)
+	}
+	le.Run(ctx)
+
+	// Drain in-flight syncs, then stop HTTP servers and release the lock.
+	runOnce.Do(func() {})
+	stopServers()
+	servers.Wait()
+	if ctx.Err() == nil {
+		klog.Fatalf("leaderelection lost")
+	}
+	releaseLock(lock)
+	klog.Info("Controller stopped")
+}
+
+// handleSignals calls cancel on the first SIGTERM or SIGINT, and exits on the second.
+func handleSignals(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-signals
+	klog.Infof("Received %v, shutting down", sig)
+	cancel()
+	sig = <-signals
+	klog.Fatalf("Received %v again, exiting", sig)
+}
+
+// serveHTTP serves handler on address until ctx is done, and then shuts down
+// the server gracefully. It serves HTTPS if certFile and keyFile are set.
+func serveHTTP(ctx context.Context, name, address string, handler http.Handler, certFile, keyFile string) {
+	server := &http.Server{Addr: address, Handler: handler}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shut down %s server: %v", name, err)
+		}
+	}()
+	var err error
+	if certFile != "" && keyFile != "" {
+		err = server.ListenAndServeTLS(certFile, keyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		klog.Fatalf("Failed to serve %s on %s: %v", name, address, err)
+	}
+}
+
+// releaseLock gives up the leader lock if it is still held by us, so another
+// replica could take over without waiting for the lease to expire.
+func releaseLock(lock rl.Interface) {
+	record, err := lock.Get()
+	if err != nil {
+		klog.Errorf("Failed to get leader lock %s: %v", lock.Describe(), err)
+		return
+	}
+	if record.HolderIdentity != lock.Identity() {
+		return
+	}
+	now := metav1.Now()
+	if err := lock.Update(rl.LeaderElectionRecord{
+		LeaseDurationSeconds: 1,
+		AcquireTime:          now,
+		RenewTime:            now,
+		LeaderTransitions:    record.LeaderTransitions,
+	}); err != nil {
+		klog.Errorf("Failed to release leader lock %s: %v", lock.Describe(), err)
+		return
+	}
+	klog.Infof("Released leader lock %s", lock.Describe())
 }
 
 func addFlags(fs *pflag.FlagSet) {
@@ -187,7 +527,38 @@ func addFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&listenAddress, "listen-address", ":8443", "The address to listen on for HTTP requests.")
 	fs.StringVar(&tlsCertFile, "tlsCertFile", "/etc/certs/tls.crt", "File containing the x509 Certificate for HTTPS.")
 	fs.StringVar(&tlsKeyFile, "tlsKeyFile", "/etc/certs/tls.key", "File containing the x509 private key to for HTTPS.")
+	fs.StringVar(&conflictPolicy, "conflict-policy", string(conflict.Warn), "How the admission webhook handles CronHPAs conflicting with other CronHPAs or HPAs of their targets, Warn or Reject.")
 	fs.StringVar(&namespace, "namespace", "kube-system", "Namespace to deploy tapp controller")
+	fs.StringVar(&metricsAddress, "metrics-address", ":8080", "The address to serve prometheus metrics on. Empty to disable.")
+	fs.StringVar(&healthAddress, "health-address", ":8081", "The address to serve /healthz, /readyz and debug endpoints on. Empty to disable.")
+	fs.StringVar(&pauseConfigMap, "pause-configmap", "cron-hpa-pause", "The name of a ConfigMap in --namespace which pauses all scaling while its \"paused\" key is \"true\". Empty to disable.")
+	fs.StringVar(&pauseFile, "pause-file", "", "A file which pauses all scaling while it exists. Empty to disable.")
+	fs.StringVar(&apiAddress, "api-address", "", "The address to serve the API for external systems on, e.g. to trigger crons or force profiles. Empty to disable.")
+	fs.StringVar(&apiTokenSecret, "api-token-secret", "", "The namespace/name of a Secret whose \"token\" key is accepted as a bearer token of the API.")
+	fs.BoolVar(&apiTokenReview, "api-token-review", false, "Accept bearer tokens of the API, e.g. of ServiceAccounts, by TokenReviews, if their users are allowed to patch the CronHPA.")
+	fs.StringVar(&apiTLSCertFile, "api-tls-cert-file", "", "File containing the x509 Certificate to serve the API over HTTPS.")
+	fs.StringVar(&apiTLSKeyFile, "api-tls-key-file", "", "File containing the x509 private key to serve the API over HTTPS.")
+	fs.BoolVar(&enableDebugSchedule, "enable-debug-schedule", false, "Serve upcoming actions of all CronHPAs as JSON at /debug/schedule on the health address")
 
 	leaderelectionconfig.BindFlags(&leaderElection, fs)
+	fs.Lookup("leader-elect-resource-lock").Usage = "The type of resource object that is used for locking during " +
+		"leader election. Supported options are `endpoints` (default), `configmaps` and `leases`."
+	fs.StringVar(&leaderElectResourceNamespace, "leader-elect-resource-namespace", "", "The namespace of resource object that is used for locking during leader election. Defaults to --namespace.")
+	fs.StringVar(&leaderElectResourceName, "leader-elect-resource-name", "cron-hpa-controller", "The name of resource object that is used for locking during leader election, or the group name of membership leases with --sharding.")
+	fs.BoolVar(&enableSharding, "sharding", false, "Share CronHPAs among all replicas coordinated through leases, instead of electing a leader. "+
+		"Lease duration, renew deadline and retry period of leader election apply to membership leases.")
+	fs.IntVar(&concurrentSyncs, "concurrent-syncs", 5, "The number of CronHPAs that are allowed to sync concurrently.")
+	fs.Float32Var(&scaleRateLimit.QPS, "scale-qps", 0, "QPS of scale operations of all CronHPAs. Zero for unlimited.")
+	fs.IntVar(&scaleRateLimit.Burst, "scale-burst", 10, "Burst of scale operations of all CronHPAs.")
+	fs.Float32Var(&scaleRateLimit.NamespaceQPS, "namespace-scale-qps", 0, "QPS of scale operations of CronHPAs in each namespace. Zero for unlimited.")
+	fs.IntVar(&scaleRateLimit.NamespaceBurst, "namespace-scale-burst", 5, "Burst of scale operations of CronHPAs in each namespace.")
+	fs.StringSliceVar(&watchNamespaces, "watch-namespaces", nil, "Comma separated namespaces to watch CronHPAs in. Empty to watch all namespaces.")
+	fs.StringVar(&cronhpaSelector, "cronhpa-selector", "", "Label selector of CronHPAs to watch. Empty to watch all CronHPAs.")
+	fs.StringVar(&controllerName, "controller-name", cronhpav1.DefaultControllerName, "The name of this controller. It only acts on CronHPAs whose spec.controllerName is this name, "+
+		"or empty if this is the default name.")
+	fs.StringVar(&notificationConfig, "notification-config", "", "Path to a YAML file of webhooks to send notifications of scale events to. Empty to disable.")
+	fs.StringVar(&cloudEventsSink, "cloudevents-sink", "", "URL to send CloudEvents of scale events to. Empty to disable.")
+	fs.StringVar(&cloudEventsMode, "cloudevents-mode", string(cloudevents.ModeBinary), "HTTP content mode of CloudEvents, binary or structured.")
+	fs.IntVar(&cloudEventsQueueSize, "cloudevents-queue-size", 1000, "The max number of CloudEvents pending to send. More are dropped.")
+	fs.BoolVar(&dumpRBAC, "dump-rbac", false, "Print RBAC manifests needed with the other flags and exit.")
 }
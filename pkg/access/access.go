@@ -0,0 +1,120 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package access reviews whether users may do what the controller does on
+// their behalf with its own permissions, e.g. scaling the targets of their
+// CronHPAs, by SubjectAccessReviews.
+package access
+
+import (
+	"fmt"
+
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	cacheddiscovery "k8s.io/client-go/discovery/cached"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+)
+
+// DeniedError is returned if a user is not allowed to access a resource.
+type DeniedError struct {
+	Message string
+}
+
+func (e *DeniedError) Error() string {
+	return e.Message
+}
+
+// Reviewer reviews access of users.
+type Reviewer struct {
+	client kubernetes.Interface
+	// restMapper maps scale targets to resources.
+	restMapper *restmapper.DeferredDiscoveryRESTMapper
+}
+
+// NewReviewer creates a Reviewer creating SubjectAccessReviews with client.
+func NewReviewer(client kubernetes.Interface) *Reviewer {
+	return &Reviewer{
+		client:     client,
+		restMapper: restmapper.NewDeferredDiscoveryRESTMapper(cacheddiscovery.NewMemCacheClient(client.Discovery())),
+	}
+}
+
+// ReviewScale returns a *DeniedError unless user may update the scale
+// subresource of the target of cronhpa, so that nobody scales through the
+// controller what they can't scale themselves.
+func (r *Reviewer) ReviewScale(user authenticationv1.UserInfo, cronhpa *cronhpav1.CronHPA) error {
+	ref := cronhpa.Spec.ScaleTargetRef
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return fmt.Errorf("invalid spec.scaleTargetRef.apiVersion: %v", err)
+	}
+	gk := schema.GroupKind{Group: gv.Group, Kind: ref.Kind}
+	mapping, err := r.restMapper.RESTMapping(gk, gv.Version)
+	if apimeta.IsNoMatchError(err) {
+		// The kind may have been added since discovery was cached
+		r.restMapper.Reset()
+		mapping, err = r.restMapper.RESTMapping(gk, gv.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find the resource of spec.scaleTargetRef: %v", err)
+	}
+	return r.Review(user, &authorizationv1.ResourceAttributes{
+		Namespace:   cronhpa.Namespace,
+		Verb:        "update",
+		Group:       mapping.Resource.Group,
+		Resource:    mapping.Resource.Resource,
+		Subresource: "scale",
+		Name:        ref.Name,
+	})
+}
+
+// Review returns a *DeniedError unless user may access attributes.
+func (r *Reviewer) Review(user authenticationv1.UserInfo, attributes *authorizationv1.ResourceAttributes) error {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := r.client.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to review access of %s: %v", user.Username, err)
+	}
+	if review.Status.Allowed {
+		return nil
+	}
+	resource := schema.GroupResource{Group: attributes.Group, Resource: attributes.Resource}.String()
+	if attributes.Subresource != "" {
+		resource += "/" + attributes.Subresource
+	}
+	if attributes.Name != "" {
+		resource += " " + attributes.Name
+	}
+	return &DeniedError{Message: fmt.Sprintf("%s is not allowed to %s %s in namespace %s",
+		user.Username, attributes.Verb, resource, attributes.Namespace)}
+}
@@ -0,0 +1,79 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package access
+
+import (
+	"testing"
+
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestReviewScale(t *testing.T) {
+	client := kubefake.NewSimpleClientset()
+	client.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true, Kind: "Deployment"}},
+	}}
+	var attributes *authorizationv1.ResourceAttributes
+	client.PrependReactor("create", "subjectaccessreviews", func(action core.Action) (bool, runtime.Object, error) {
+		review := action.(core.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attributes = review.Spec.ResourceAttributes
+		review.Status.Allowed = review.Spec.User == "alice"
+		return true, review, nil
+	})
+	reviewer := NewReviewer(client)
+
+	newCronHPA := func(apiVersion, kind string) *cronhpav1.CronHPA {
+		return &cronhpav1.CronHPA{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec: cronhpav1.CronHPASpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: apiVersion, Kind: kind, Name: "web"},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		cronhpa *cronhpav1.CronHPA
+		user    string
+		allowed bool
+	}{
+		{"allowed", newCronHPA("apps/v1", "Deployment"), "alice", true},
+		{"denied", newCronHPA("apps/v1", "Deployment"), "bob", false},
+		{"unknown kind", newCronHPA("example.com/v1", "Widget"), "alice", false},
+	}
+	for _, test := range tests {
+		err := reviewer.ReviewScale(authenticationv1.UserInfo{Username: test.user}, test.cronhpa)
+		if (err == nil) != test.allowed {
+			t.Errorf("%s: expected allowed %v, got error %v", test.name, test.allowed, err)
+		}
+	}
+	expected := authorizationv1.ResourceAttributes{
+		Namespace: "default", Verb: "update", Group: "apps", Resource: "deployments", Subresource: "scale", Name: "web",
+	}
+	if attributes == nil || *attributes != expected {
+		t.Errorf("expected attributes %+v, got %+v", expected, attributes)
+	}
+}
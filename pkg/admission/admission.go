@@ -18,35 +18,82 @@
 package admission
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"reflect"
+	"sync"
+	"time"
 
+	"tkestack.io/cron-hpa/pkg/access"
 	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller"
 	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	clientset "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+	"tkestack.io/cron-hpa/pkg/conflict"
+	"tkestack.io/cron-hpa/pkg/metrics"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	admissionregistrationv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 )
 
 const (
 	validatingWebhookConfiguration = "cron-hpa-admission"
+	mutatingWebhookConfiguration   = "cron-hpa-admission"
+	// namespaceNameLabel is set on namespaces to their names by kube-apiserver
+	// since 1.21, see minScopedVersion.
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+	// shutdownTimeout is how long the server waits for active requests on shutdown.
+	shutdownTimeout = 10 * time.Second
 )
 
 var validatePath = "/validate/cronhpa"
+var mutatePath = "/mutate/cronhpa"
 var failPolicy admissionregistrationv1beta1.FailurePolicyType = "Fail"
 
-// Register registers the validatingWebhookConfiguration to kube-apiserver
+// minScopedVersion is the first version of kube-apiserver setting
+// namespaceNameLabel, by which webhooks scoped to watched namespaces select
+// them. Older ones would never call the webhooks.
+var minScopedVersion = version.MustParseGeneric("1.21.0")
+
+// CheckScopedSupport returns an error if the webhooks can't be scoped to
+// namespaces by the kube-apiserver of client.
+func CheckScopedSupport(client kubernetes.Interface) error {
+	info, err := client.Discovery().ServerVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get the version of kube-apiserver: %v", err)
+	}
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return err
+	}
+	if !serverVersion.AtLeast(minScopedVersion) {
+		return fmt.Errorf("kube-apiserver %s doesn't set the %s label of namespaces, which needs %s or later",
+			info.GitVersion, namespaceNameLabel, minScopedVersion)
+	}
+	return nil
+}
+
+// Register registers the validatingWebhookConfiguration and the
+// mutatingWebhookConfiguration to kube-apiserver.
+// If watchNamespaces is not empty, the webhooks only apply to them, and the
+// configurations are named after namespace, so that installations in different
+// namespaces don't overwrite each other.
 // Note: always return err as nil, it will be used by wait.PollUntil().
-func Register(clientset *kubernetes.Clientset, namespace string, caFile string) (bool, error) {
-	klog.Infof("Starting to register validatingWebhookConfiguration")
+func Register(clientset *kubernetes.Clientset, namespace string, caFile string, watchNamespaces []string) (bool, error) {
+	klog.Infof("Starting to register webhook configurations")
 	defer func() {
-		klog.Infof("Finished registering validatingWebhookConfiguration")
+		klog.Infof("Finished registering webhook configurations")
 	}()
 
 	caCert, err := ioutil.ReadFile(caFile)
@@ -55,87 +102,231 @@ func Register(clientset *kubernetes.Clientset, namespace string, caFile string)
 		return false, nil
 	}
 
-	webhookConfig := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: validatingWebhookConfiguration,
-		},
-		Webhooks: []admissionregistrationv1beta1.Webhook{
-			{
-				Name: fmt.Sprintf("cron-hpa-controller.%s.svc", namespace),
-				Rules: []admissionregistrationv1beta1.RuleWithOperations{{
-					Operations: []admissionregistrationv1beta1.OperationType{admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update},
-					Rule: admissionregistrationv1beta1.Rule{
-						APIGroups:   []string{cronhpacontroller.GroupName},
-						APIVersions: []string{"v1"},
-						Resources:   []string{"cronhpas"},
-					},
-				}},
-				FailurePolicy: &failPolicy,
-				ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
-					Service: &admissionregistrationv1beta1.ServiceReference{
-						Namespace: namespace,
-						Name:      "cron-hpa-controller",
-						Path:      &validatePath,
-					},
-					CABundle: caCert,
+	validatingName := validatingWebhookConfiguration
+	mutatingName := mutatingWebhookConfiguration
+	var namespaceSelector *metav1.LabelSelector
+	if len(watchNamespaces) > 0 {
+		validatingName = validatingWebhookConfiguration + "-" + namespace
+		mutatingName = mutatingWebhookConfiguration + "-" + namespace
+		namespaceSelector = &metav1.LabelSelector{
+			MatchExpressions: []metav1.LabelSelectorRequirement{{
+				Key:      namespaceNameLabel,
+				Operator: metav1.LabelSelectorOpIn,
+				Values:   watchNamespaces,
+			}},
+		}
+	}
+	webhook := func(path *string) admissionregistrationv1beta1.Webhook {
+		return admissionregistrationv1beta1.Webhook{
+			Name: fmt.Sprintf("cron-hpa-controller.%s.svc", namespace),
+			Rules: []admissionregistrationv1beta1.RuleWithOperations{{
+				Operations: []admissionregistrationv1beta1.OperationType{admissionregistrationv1beta1.Create, admissionregistrationv1beta1.Update},
+				Rule: admissionregistrationv1beta1.Rule{
+					APIGroups:   []string{cronhpacontroller.GroupName},
+					APIVersions: []string{"v1"},
+					Resources:   []string{"cronhpas"},
 				},
+			}},
+			FailurePolicy:     &failPolicy,
+			NamespaceSelector: namespaceSelector,
+			ClientConfig: admissionregistrationv1beta1.WebhookClientConfig{
+				Service: &admissionregistrationv1beta1.ServiceReference{
+					Namespace: namespace,
+					Name:      "cron-hpa-controller",
+					Path:      path,
+				},
+				CABundle: caCert,
 			},
-		},
+		}
 	}
 
-	client := clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations()
-	if present, err := client.Get(validatingWebhookConfiguration, metav1.GetOptions{}); err == nil {
-		if !reflect.DeepEqual(present.Webhooks, webhookConfig.Webhooks) {
-			klog.V(1).Infof("Update validationWebhookConfiguration from %+v to %+v", present, webhookConfig)
-			webhookConfig.ResourceVersion = present.ResourceVersion
-			if _, err := client.Update(webhookConfig); err != nil {
+	validatingConfig := &admissionregistrationv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: validatingName,
+		},
+		Webhooks: []admissionregistrationv1beta1.Webhook{webhook(&validatePath)},
+	}
+	validatingClient := clientset.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations()
+	if present, err := validatingClient.Get(validatingName, metav1.GetOptions{}); err == nil {
+		if !reflect.DeepEqual(present.Webhooks, validatingConfig.Webhooks) {
+			klog.V(1).Infof("Update validationWebhookConfiguration from %+v to %+v", present, validatingConfig)
+			validatingConfig.ResourceVersion = present.ResourceVersion
+			if _, err := validatingClient.Update(validatingConfig); err != nil {
 				klog.Errorf("Failed to update validationWebhookConfiguration: %v", err)
 				return false, nil
 			}
 		}
 	} else {
-		if _, err := client.Create(webhookConfig); err != nil {
+		if _, err := validatingClient.Create(validatingConfig); err != nil {
 			klog.Errorf("Failed to create validatingWebhookConfiguration: %v", err)
 			return false, nil
 		}
 	}
 
+	mutatingConfig := &admissionregistrationv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: mutatingName,
+		},
+		Webhooks: []admissionregistrationv1beta1.Webhook{webhook(&mutatePath)},
+	}
+	mutatingClient := clientset.AdmissionregistrationV1beta1().MutatingWebhookConfigurations()
+	if present, err := mutatingClient.Get(mutatingName, metav1.GetOptions{}); err == nil {
+		if !reflect.DeepEqual(present.Webhooks, mutatingConfig.Webhooks) {
+			klog.V(1).Infof("Update mutatingWebhookConfiguration from %+v to %+v", present, mutatingConfig)
+			mutatingConfig.ResourceVersion = present.ResourceVersion
+			if _, err := mutatingClient.Update(mutatingConfig); err != nil {
+				klog.Errorf("Failed to update mutatingWebhookConfiguration: %v", err)
+				return false, nil
+			}
+		}
+	} else {
+		if _, err := mutatingClient.Create(mutatingConfig); err != nil {
+			klog.Errorf("Failed to create mutatingWebhookConfiguration: %v", err)
+			return false, nil
+		}
+	}
+
 	return true, nil
 }
 
+// jsonPatchOperation is an operation of a JSON patch, see https://tools.ietf.org/html/rfc6902.
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
 // Server will start a https server for admitting.
 type Server struct {
 	listenAddress string
 	certFile      string
 	keyFile       string
+	// watchNamespaces and selector limit the cronhpas validated by the server,
+	// and others are allowed as they are out of the controller's scope.
+	watchNamespaces sets.String
+	selector        labels.Selector
+	// access reviews whether users may scale the targets of their cronhpas.
+	access *access.Reviewer
+	// client reads CronHPAPolicies, and policyNamespace is the namespace of
+	// the default ones.
+	client          clientset.Interface
+	policyNamespace string
+	// conflicts finds CronHPAs and HPAs scaling the same target as a
+	// cronhpa, which are handled by conflictPolicy.
+	conflicts      *conflict.Index
+	conflictPolicy conflict.Policy
+
+	lock sync.RWMutex
+	// err is the error which stopped the server from serving.
+	err error
 }
 
-// NewServer create a new Server for admitting.
-func NewServer(listenAddress, certFile, keyFile string) (*Server, error) {
+// NewServer create a new Server for admitting. Empty watchNamespaces means all namespaces.
+func NewServer(listenAddress, certFile, keyFile string, watchNamespaces []string, selector labels.Selector,
+	kubeClient kubernetes.Interface, client clientset.Interface, policyNamespace string, conflicts *conflict.Index, conflictPolicy conflict.Policy) (*Server, error) {
 	server := &Server{
-		listenAddress: listenAddress,
-		certFile:      certFile,
-		keyFile:       keyFile,
+		listenAddress:   listenAddress,
+		certFile:        certFile,
+		keyFile:         keyFile,
+		watchNamespaces: sets.NewString(watchNamespaces...),
+		selector:        selector,
+		access:          access.NewReviewer(kubeClient),
+		client:          client,
+		policyNamespace: policyNamespace,
+		conflicts:       conflicts,
+		conflictPolicy:  conflictPolicy,
 	}
 
 	return server, nil
 }
 
-// Run starts informers, and listens for accepting request.
-func (ws *Server) Run(stopCh <-chan struct{}) {
+// Run listens for accepting request until ctx is done, and then shuts down
+// the server gracefully.
+func (ws *Server) Run(ctx context.Context) {
 	mux := http.NewServeMux()
 	mux.HandleFunc(validatePath, func(writer http.ResponseWriter, request *http.Request) {
-		Serve(writer, request, admitCronHPA)
+		Serve(writer, request, instrument(ws.admitCronHPA))
+	})
+	mux.HandleFunc(mutatePath, func(writer http.ResponseWriter, request *http.Request) {
+		Serve(writer, request, instrument(ws.mutateCronHPA))
 	})
 
 	server := &http.Server{
 		Addr:    ws.listenAddress,
 		Handler: mux,
 	}
-	klog.Fatal(server.ListenAndServeTLS(ws.certFile, ws.keyFile))
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			klog.Errorf("Failed to shut down admission server: %v", err)
+		}
+	}()
+	if err := server.ListenAndServeTLS(ws.certFile, ws.keyFile); err != nil && err != http.ErrServerClosed {
+		klog.Errorf("Admission server stopped serving: %v", err)
+		ws.lock.Lock()
+		ws.err = err
+		ws.lock.Unlock()
+	}
+}
+
+// Check returns an error if the server has stopped serving.
+func (ws *Server) Check() error {
+	ws.lock.RLock()
+	defer ws.lock.RUnlock()
+	return ws.err
 }
 
-func admitCronHPA(ar *admissionv1beta1.AdmissionReview) *admissionv1beta1.AdmissionResponse {
+// CheckCertificate returns an error if the serving certificate can't be loaded
+// or is not valid now.
+func (ws *Server) CheckCertificate() error {
+	cert, err := tls.LoadX509KeyPair(ws.certFile, ws.keyFile)
+	if err != nil {
+		return err
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("certificate %s is not valid until %v", ws.certFile, leaf.NotBefore)
+	}
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate %s expired at %v", ws.certFile, leaf.NotAfter)
+	}
+	return nil
+}
+
+// instrument records latency and rejections of admitter.
+func instrument(admitter func(*admissionv1beta1.AdmissionReview) *admissionv1beta1.AdmissionResponse) func(*admissionv1beta1.AdmissionReview) *admissionv1beta1.AdmissionResponse {
+	return func(ar *admissionv1beta1.AdmissionReview) *admissionv1beta1.AdmissionResponse {
+		start := time.Now()
+		var operation string
+		if ar.Request != nil {
+			operation = string(ar.Request.Operation)
+		}
+
+		response := admitter(ar)
+
+		metrics.AdmissionDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+		if !response.Allowed {
+			metrics.AdmissionRejections.WithLabelValues(operation).Inc()
+		}
+		return response
+	}
+}
+
+// inScope returns true if cronHPA is watched by the controller.
+func (ws *Server) inScope(cronHPA *cronhpav1.CronHPA) bool {
+	if ws.watchNamespaces.Len() > 0 && !ws.watchNamespaces.Has(cronHPA.Namespace) {
+		return false
+	}
+	return ws.selector.Matches(labels.Set(cronHPA.Labels))
+}
+
+func (ws *Server) admitCronHPA(ar *admissionv1beta1.AdmissionReview) *admissionv1beta1.AdmissionResponse {
 	klog.V(4).Info("Admitting CronHPA")
 
 	reviewResponse := &admissionv1beta1.AdmissionResponse{}
@@ -147,6 +338,87 @@ func admitCronHPA(ar *admissionv1beta1.AdmissionReview) *admissionv1beta1.Admiss
 		klog.Errorf("Failed to unmarshal CronHPA from %s: %v", raw, err)
 		return ToAdmissionResponse(err)
 	}
+	if cronHPA.Namespace == "" {
+		cronHPA.Namespace = ar.Request.Namespace
+	}
+	if !ws.inScope(&cronHPA) {
+		klog.V(4).Infof("Allow CronHPA %s/%s out of scope", cronHPA.Namespace, cronHPA.Name)
+		return reviewResponse
+	}
+	// old is the CronHPA before an update, or nil on creation. Checks of what
+	// is unchanged are skipped, so that e.g. status updates of the controller
+	// are not rejected by policies added since the CronHPA was admitted.
+	var old *cronhpav1.CronHPA
+	if ar.Request.Operation == admissionv1beta1.Update {
+		old = &cronhpav1.CronHPA{}
+		if err := json.Unmarshal(ar.Request.OldObject.Raw, old); err != nil {
+			klog.Errorf("Failed to unmarshal old CronHPA from %s: %v", ar.Request.OldObject.Raw, err)
+			return ToAdmissionResponse(err)
+		}
+	}
+	if err := validateCronHPA(&cronHPA); err != nil {
+		return ToAdmissionResponse(err)
+	}
+	if scalingChanged(old, &cronHPA) {
+		if err := ws.access.ReviewScale(ar.Request.UserInfo, &cronHPA); err != nil {
+			return ToAdmissionResponse(err)
+		}
+	}
+	if old == nil || !apiequality.Semantic.DeepEqual(old.Spec.PreScale, cronHPA.Spec.PreScale) ||
+		!apiequality.Semantic.DeepEqual(old.Spec.PostScale, cronHPA.Spec.PostScale) {
+		if err := ws.authorizeHookJobs(&cronHPA, ar.Request.UserInfo); err != nil {
+			return ToAdmissionResponse(err)
+		}
+	}
+	if old == nil || !apiequality.Semantic.DeepEqual(old.Spec, cronHPA.Spec) ||
+		old.Annotations[cronhpav1.OverrideAnnotation] != cronHPA.Annotations[cronhpav1.OverrideAnnotation] {
+		if err := ws.validatePolicies(&cronHPA); err != nil {
+			return ToAdmissionResponse(err)
+		}
+	}
+	// Replicas of crons depend on profiles
+	if old == nil || !apiequality.Semantic.DeepEqual(old.Spec.ScaleTargetRef, cronHPA.Spec.ScaleTargetRef) ||
+		!apiequality.Semantic.DeepEqual(old.Spec.Crons, cronHPA.Spec.Crons) ||
+		!apiequality.Semantic.DeepEqual(old.Spec.Profiles, cronHPA.Spec.Profiles) {
+		if err := ws.checkConflicts(&cronHPA, reviewResponse); err != nil {
+			return ToAdmissionResponse(err)
+		}
+	}
+
+	return reviewResponse
+}
+
+// mutateCronHPA defaults spec.controllerName, so that it is explicit which
+// controller acts on the CronHPA.
+func (ws *Server) mutateCronHPA(ar *admissionv1beta1.AdmissionReview) *admissionv1beta1.AdmissionResponse {
+	klog.V(4).Info("Mutating CronHPA")
+
+	reviewResponse := &admissionv1beta1.AdmissionResponse{}
+	reviewResponse.Allowed = true
+
+	var cronHPA cronhpav1.CronHPA
+	raw := ar.Request.Object.Raw
+	if err := json.Unmarshal(raw, &cronHPA); err != nil {
+		klog.Errorf("Failed to unmarshal CronHPA from %s: %v", raw, err)
+		return ToAdmissionResponse(err)
+	}
+	if cronHPA.Namespace == "" {
+		cronHPA.Namespace = ar.Request.Namespace
+	}
+	if !ws.inScope(&cronHPA) || cronHPA.Spec.ControllerName != "" {
+		return reviewResponse
+	}
 
+	patch, err := json.Marshal([]jsonPatchOperation{{
+		Op:    "add",
+		Path:  "/spec/controllerName",
+		Value: cronhpav1.DefaultControllerName,
+	}})
+	if err != nil {
+		return ToAdmissionResponse(err)
+	}
+	patchType := admissionv1beta1.PatchTypeJSONPatch
+	reviewResponse.Patch = patch
+	reviewResponse.PatchType = &patchType
 	return reviewResponse
 }
@@ -0,0 +1,68 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package admission
+
+import (
+	"fmt"
+
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+)
+
+// scalingChanged returns true if cronHPA is created, or updated from old in a
+// way that changes how it scales: its target, crons, profiles, or a newly set
+// trigger or override annotation.
+func scalingChanged(old, cronHPA *cronhpav1.CronHPA) bool {
+	if old == nil {
+		return true
+	}
+	if !apiequality.Semantic.DeepEqual(old.Spec.ScaleTargetRef, cronHPA.Spec.ScaleTargetRef) ||
+		!apiequality.Semantic.DeepEqual(old.Spec.Crons, cronHPA.Spec.Crons) ||
+		!apiequality.Semantic.DeepEqual(old.Spec.Profiles, cronHPA.Spec.Profiles) {
+		return true
+	}
+	// Removals of annotations, e.g. by the controller accepting them, don't scale
+	for _, key := range []string{cronhpav1.TriggerAnnotation, cronhpav1.OverrideAnnotation} {
+		if value := cronHPA.Annotations[key]; value != "" && value != old.Annotations[key] {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeHookJobs returns an error unless user may create Jobs in the
+// namespace of cronHPA if it has Job hooks, as the controller creates them
+// with its own permissions.
+func (ws *Server) authorizeHookJobs(cronHPA *cronhpav1.CronHPA, user authenticationv1.UserInfo) error {
+	preScale, postScale := cronHPA.Spec.PreScale, cronHPA.Spec.PostScale
+	if (preScale == nil || preScale.Job == nil) && (postScale == nil || postScale.Job == nil) {
+		return nil
+	}
+	if err := ws.access.Review(user, &authorizationv1.ResourceAttributes{
+		Namespace: cronHPA.Namespace,
+		Verb:      "create",
+		Group:     "batch",
+		Resource:  "jobs",
+	}); err != nil {
+		return fmt.Errorf("job hooks: %v", err)
+	}
+	return nil
+}
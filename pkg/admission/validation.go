@@ -0,0 +1,222 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package admission
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/conflict"
+	"tkestack.io/cron-hpa/pkg/policy"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+const (
+	// maxHTTPHookTimeoutSeconds bounds timeoutSeconds of HTTP hooks, whose
+	// calls hold a connection of the controller.
+	maxHTTPHookTimeoutSeconds = 300
+	// maxJobHookTimeoutSeconds bounds timeoutSeconds of Job hooks, while the
+	// schedule stays due.
+	maxJobHookTimeoutSeconds = 3600
+)
+
+// validateCronHPA returns an error if the spec of cronHPA is invalid.
+func validateCronHPA(cronHPA *cronhpav1.CronHPA) error {
+	names := sets.NewString()
+	for i := range cronHPA.Spec.Crons {
+		name := cronhpav1.GetCronName(&cronHPA.Spec.Crons[i])
+		if names.Has(name) {
+			return fmt.Errorf("duplicate cron name %q, crons with the same schedule need distinct names", name)
+		}
+		names.Insert(name)
+	}
+	for _, annotation := range []string{cronhpav1.TriggerAnnotation, cronhpav1.SkipNextAnnotation} {
+		if name, ok := cronHPA.Annotations[annotation]; ok && !names.Has(name) {
+			return fmt.Errorf("no cron named %q for annotation %s", name, annotation)
+		}
+	}
+	if value, ok := cronHPA.Annotations[cronhpav1.OverrideAnnotation]; ok {
+		override := &cronhpav1.ReplicasOverride{}
+		if err := json.Unmarshal([]byte(value), override); err != nil {
+			return fmt.Errorf("invalid annotation %s: %v", cronhpav1.OverrideAnnotation, err)
+		}
+		if override.Replicas < 0 || override.Until.IsZero() {
+			return fmt.Errorf("annotation %s needs non-negative replicas and an until time", cronhpav1.OverrideAnnotation)
+		}
+		if _, ok := cronHPA.Spec.Profiles[override.Profile]; override.Profile != "" && !ok {
+			return fmt.Errorf("no profile named %q for annotation %s", override.Profile, cronhpav1.OverrideAnnotation)
+		}
+	}
+	for name, profile := range cronHPA.Spec.Profiles {
+		if profile.Replicas < 0 {
+			return fmt.Errorf("spec.profiles[%s].replicas must not be negative", name)
+		}
+	}
+	for i := range cronHPA.Spec.Crons {
+		cron := &cronHPA.Spec.Crons[i]
+		if _, ok := cronHPA.Spec.Profiles[cron.Profile]; cron.Profile != "" && !ok {
+			return fmt.Errorf("no profile named %q for cron %s", cron.Profile, cronhpav1.GetCronName(cron))
+		}
+	}
+	if cronHPA.Spec.NotifyBefore != nil && cronHPA.Spec.NotifyBefore.Duration < 0 {
+		return fmt.Errorf("spec.notifyBefore must not be negative")
+	}
+	if cronHPA.Spec.MaxJitterSeconds != nil && *cronHPA.Spec.MaxJitterSeconds < 0 {
+		return fmt.Errorf("spec.maxJitterSeconds must not be negative")
+	}
+	switch cronHPA.Spec.MissedSchedulePolicy {
+	case "", cronhpav1.MissedScheduleRunLatest, cronhpav1.MissedScheduleSkip:
+	default:
+		return fmt.Errorf("unsupported spec.missedSchedulePolicy %q", cronHPA.Spec.MissedSchedulePolicy)
+	}
+	switch cronHPA.Spec.QuotaPolicy {
+	case "", cronhpav1.QuotaPolicyIgnore, cronhpav1.QuotaPolicyClamp, cronhpav1.QuotaPolicyRefuse:
+	default:
+		return fmt.Errorf("unsupported spec.quotaPolicy %q", cronHPA.Spec.QuotaPolicy)
+	}
+	if cost := cronHPA.Spec.PodDeletionCost; cost != nil {
+		switch cost.Source {
+		case cronhpav1.PodDeletionCostAge:
+		case cronhpav1.PodDeletionCostAnnotation:
+			if cost.Annotation == "" {
+				return fmt.Errorf("spec.podDeletionCost.annotation is required by the Annotation source")
+			}
+		case cronhpav1.PodDeletionCostHTTP:
+			if cost.HTTP == nil || cost.HTTP.Port <= 0 || cost.HTTP.Port > 65535 {
+				return fmt.Errorf("spec.podDeletionCost.http with a valid port is required by the HTTP source")
+			}
+			if scheme := cost.HTTP.Scheme; scheme != "" && scheme != corev1.URISchemeHTTP && scheme != corev1.URISchemeHTTPS {
+				return fmt.Errorf("unsupported spec.podDeletionCost.http.scheme %q", scheme)
+			}
+		default:
+			return fmt.Errorf("unsupported spec.podDeletionCost.source %q", cost.Source)
+		}
+	}
+	if drain := cronHPA.Spec.Drain; drain != nil && drain.TimeoutSeconds != nil && *drain.TimeoutSeconds < 0 {
+		return fmt.Errorf("spec.drain.timeoutSeconds must not be negative")
+	}
+	if hook := cronHPA.Spec.PreScale; hook != nil {
+		if err := validateScaleHook(hook, cronHPA.Namespace); err != nil {
+			return fmt.Errorf("invalid spec.preScale: %v", err)
+		}
+	}
+	if hook := cronHPA.Spec.PostScale; hook != nil {
+		if err := validateScaleHook(hook, cronHPA.Namespace); err != nil {
+			return fmt.Errorf("invalid spec.postScale: %v", err)
+		}
+	}
+	return nil
+}
+
+// validatePolicies returns an error if cronHPA violates the CronHPAPolicies of
+// its namespace.
+func (ws *Server) validatePolicies(cronHPA *cronhpav1.CronHPA) error {
+	policies, err := policy.Get(ws.client, cronHPA.Namespace, ws.policyNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to get cronhpapolicies: %v", err)
+	}
+	if err := policy.Validate(cronHPA, policies, time.Now()); err != nil {
+		return err
+	}
+	if value, ok := cronHPA.Annotations[cronhpav1.OverrideAnnotation]; ok {
+		// It has been validated by validateCronHPA
+		override := &cronhpav1.ReplicasOverride{}
+		json.Unmarshal([]byte(value), override)
+		replicas := override.Replicas
+		if override.Profile != "" {
+			replicas = cronHPA.Spec.Profiles[override.Profile].Replicas
+		}
+		if err := policy.ValidateReplicas(replicas, policies); err != nil {
+			return fmt.Errorf("annotation %s: %v", cronhpav1.OverrideAnnotation, err)
+		}
+	}
+	return nil
+}
+
+// checkConflicts returns an error if other CronHPAs or HPAs conflict with
+// cronHPA and conflictPolicy is Reject. Otherwise conflicts are logged, and
+// recorded as an audit annotation of response.
+func (ws *Server) checkConflicts(cronHPA *cronhpav1.CronHPA, response *admissionv1beta1.AdmissionResponse) error {
+	if ws.conflicts == nil {
+		return nil
+	}
+	if !ws.conflicts.HasSynced() {
+		klog.Warningf("Skip checking conflicts of CronHPA %s/%s before informers have synced", cronHPA.Namespace, cronHPA.Name)
+		return nil
+	}
+	conflicts, err := ws.conflicts.Find(cronHPA)
+	if err != nil {
+		return fmt.Errorf("failed to find conflicts: %v", err)
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	message := conflict.Describe(conflicts)
+	if ws.conflictPolicy == conflict.Reject {
+		return fmt.Errorf("conflicts with %s", message)
+	}
+	klog.Warningf("Allow CronHPA %s/%s conflicting with %s", cronHPA.Namespace, cronHPA.Name, message)
+	response.AuditAnnotations = map[string]string{"conflict": message}
+	return nil
+}
+
+// validateScaleHook returns an error if hook of a CronHPA in namespace is
+// invalid.
+func validateScaleHook(hook *cronhpav1.ScaleHook, namespace string) error {
+	if (hook.HTTP == nil) == (hook.Job == nil) {
+		return fmt.Errorf("exactly one of http and job is required")
+	}
+	if http := hook.HTTP; http != nil {
+		if http.Name == "" {
+			return fmt.Errorf("http.name is required")
+		}
+		if http.Namespace != "" && http.Namespace != namespace {
+			return fmt.Errorf("http.namespace must be the namespace of the CronHPA %s", namespace)
+		}
+		if http.Port <= 0 || http.Port > 65535 {
+			return fmt.Errorf("invalid http.port %d", http.Port)
+		}
+		if scheme := http.Scheme; scheme != "" && scheme != corev1.URISchemeHTTP && scheme != corev1.URISchemeHTTPS {
+			return fmt.Errorf("unsupported http.scheme %q", scheme)
+		}
+	}
+	if hook.Job != nil && len(hook.Job.Spec.Template.Spec.Containers) == 0 {
+		return fmt.Errorf("job.spec.template has no containers")
+	}
+	if timeout := hook.TimeoutSeconds; timeout != nil {
+		max := int32(maxJobHookTimeoutSeconds)
+		if hook.HTTP != nil {
+			max = maxHTTPHookTimeoutSeconds
+		}
+		if *timeout < 1 || *timeout > max {
+			return fmt.Errorf("timeoutSeconds must be between 1 and %d", max)
+		}
+	}
+	switch hook.FailurePolicy {
+	case "", cronhpav1.HookFailurePolicyIgnore, cronhpav1.HookFailurePolicyAbort:
+	default:
+		return fmt.Errorf("unsupported failurePolicy %q", hook.FailurePolicy)
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package api serves an HTTP API of the controller for external systems to
+// act on CronHPAs, e.g. to trigger a cron or force a profile. Requests are
+// authenticated by bearer tokens, and logged for audit.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	clientset "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+const (
+	// profilePath is the prefix of PUT /profile/{namespace}/{cronhpa}.
+	profilePath = "/profile/"
+	// triggerPath is the prefix of POST /trigger/{namespace}/{cronhpa}/{cron}.
+	triggerPath = "/trigger/"
+)
+
+// ProfileRequest is the body of PUT /profile/{namespace}/{cronhpa}, which
+// forces a profile until a time, or for a duration.
+type ProfileRequest struct {
+	Profile  string          `json:"profile"`
+	Until    *metav1.Time    `json:"until,omitempty"`
+	Duration metav1.Duration `json:"duration,omitempty"`
+}
+
+// Handler serves the API.
+type Handler struct {
+	client         clientset.Interface
+	authenticators []Authenticator
+	mux            *http.ServeMux
+}
+
+// NewHandler creates a Handler acting on CronHPAs with client. Requests are
+// accepted if any of authenticators accepts their bearer token.
+func NewHandler(client clientset.Interface, authenticators []Authenticator) *Handler {
+	h := &Handler{client: client, authenticators: authenticators, mux: http.NewServeMux()}
+	h.mux.HandleFunc(profilePath, h.audit(h.serveProfile))
+	h.mux.HandleFunc(triggerPath, h.audit(h.serveTrigger))
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// auditWriter records the user and status of a request for the audit log.
+type auditWriter struct {
+	http.ResponseWriter
+	user   string
+	status int
+}
+
+func (w *auditWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// audit logs every request served by serve.
+func (h *Handler) audit(serve func(w *auditWriter, r *http.Request)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		aw := &auditWriter{ResponseWriter: w, status: http.StatusOK}
+		serve(aw, r)
+		klog.Infof("Audit: user %q from %s %s %s: %d", aw.user, r.RemoteAddr, r.Method, r.URL.Path, aw.status)
+	}
+}
+
+// authenticate authenticates r for acting on cronhpa namespace/name, and
+// writes the error if it fails.
+func (h *Handler) authenticate(w *auditWriter, r *http.Request, namespace, name string) bool {
+	token := bearerToken(r)
+	if token == "" {
+		http.Error(w, "missing bearer token", http.StatusUnauthorized)
+		return false
+	}
+	var err error = &AuthError{Code: http.StatusUnauthorized, Message: "invalid bearer token"}
+	for _, authenticator := range h.authenticators {
+		var user string
+		if user, err = authenticator.Authenticate(token, namespace, name); err == nil {
+			w.user = user
+			return true
+		}
+		if _, ok := err.(*AuthError); !ok {
+			klog.Errorf("Failed to authenticate request to cronhpa %s/%s: %v", namespace, name, err)
+		}
+	}
+	if authErr, ok := err.(*AuthError); ok {
+		http.Error(w, authErr.Message, authErr.Code)
+	} else {
+		http.Error(w, "failed to authenticate", http.StatusInternalServerError)
+	}
+	return false
+}
+
+// serveTrigger runs a cron of a CronHPA once by its TriggerAnnotation, so it
+// scales the same way as a scheduled run.
+func (h *Handler) serveTrigger(w *auditWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := pathParts(r, triggerPath)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		http.Error(w, "expected /trigger/{namespace}/{cronhpa}/{cron}", http.StatusNotFound)
+		return
+	}
+	namespace, name, cronName := parts[0], parts[1], parts[2]
+	if !h.authenticate(w, r, namespace, name) {
+		return
+	}
+
+	cronhpa, err := h.client.CronhpacontrollerV1().CronHPAs(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	found := false
+	for i := range cronhpa.Spec.Crons {
+		if cronhpav1.GetCronName(&cronhpa.Spec.Crons[i]) == cronName {
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("no cron named %q", cronName), http.StatusNotFound)
+		return
+	}
+	if err := h.patchAnnotation(namespace, name, cronhpav1.TriggerAnnotation, cronName); err != nil {
+		writeError(w, err)
+		return
+	}
+	klog.Infof("Triggered cron %s of cronhpa %s/%s by %s", cronName, namespace, name, w.user)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// serveProfile forces a profile of a CronHPA by its OverrideAnnotation.
+func (h *Handler) serveProfile(w *auditWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "only PUT is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := pathParts(r, profilePath)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, "expected /profile/{namespace}/{cronhpa}", http.StatusNotFound)
+		return
+	}
+	namespace, name := parts[0], parts[1]
+	if !h.authenticate(w, r, namespace, name) {
+		return
+	}
+
+	request := &ProfileRequest{}
+	if err := json.NewDecoder(r.Body).Decode(request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid body: %v", err), http.StatusBadRequest)
+		return
+	}
+	override := cronhpav1.ReplicasOverride{Profile: request.Profile, Source: cronhpav1.ProfileSourceAPI}
+	switch {
+	case request.Until != nil && request.Duration.Duration == 0:
+		override.Until = *request.Until
+	case request.Until == nil && request.Duration.Duration > 0:
+		override.Until = metav1.NewTime(time.Now().Add(request.Duration.Duration))
+	default:
+		http.Error(w, "exactly one of until and a positive duration is required", http.StatusBadRequest)
+		return
+	}
+
+	cronhpa, err := h.client.CronhpacontrollerV1().CronHPAs(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, ok := cronhpa.Spec.Profiles[request.Profile]; !ok {
+		http.Error(w, fmt.Sprintf("no profile named %q", request.Profile), http.StatusNotFound)
+		return
+	}
+	value, err := json.Marshal(override)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := h.patchAnnotation(namespace, name, cronhpav1.OverrideAnnotation, string(value)); err != nil {
+		writeError(w, err)
+		return
+	}
+	klog.Infof("Forced profile %s of cronhpa %s/%s until %s by %s", request.Profile, namespace, name, override.Until.Format(time.RFC3339), w.user)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(value)
+}
+
+// pathParts splits the path of r after prefix, unescaping each part, so that
+// crons named by their schedules could be given with escaped slashes.
+func pathParts(r *http.Request, prefix string) []string {
+	parts := strings.Split(strings.TrimPrefix(r.URL.EscapedPath(), prefix), "/")
+	for i, part := range parts {
+		unescaped, err := url.PathUnescape(part)
+		if err != nil {
+			return nil
+		}
+		parts[i] = unescaped
+	}
+	return parts
+}
+
+// patchAnnotation sets an annotation of cronhpa namespace/name.
+func (h *Handler) patchAnnotation(namespace, name, key, value string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{key: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = h.client.CronhpacontrollerV1().CronHPAs(namespace).Patch(name, types.MergePatchType, patch)
+	return err
+}
+
+// writeError writes err of the apiserver with its status code.
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	if status, ok := err.(errors.APIStatus); ok {
+		code = int(status.Status().Code)
+	}
+	http.Error(w, err.Error(), code)
+}
@@ -0,0 +1,89 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cronhpav1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/client/clientset/versioned/fake"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	core "k8s.io/client-go/testing"
+)
+
+func TestServeTrigger(t *testing.T) {
+	cronhpa := &cronhpav1.CronHPA{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+		Spec: cronhpav1.CronHPASpec{
+			Crons: []cronhpav1.Cron{{Name: "morning", Schedule: "0 8 * * *", TargetReplicas: 5}},
+		},
+	}
+	var patch string
+	client := fake.NewSimpleClientset()
+	if _, err := client.CronhpacontrollerV1().CronHPAs("default").Create(cronhpa); err != nil {
+		t.Fatal(err)
+	}
+	// The fake clientset doesn't apply merge patches
+	client.PrependReactor("patch", "cronhpas", func(action core.Action) (bool, runtime.Object, error) {
+		patch = string(action.(core.PatchAction).GetPatch())
+		return true, cronhpa, nil
+	})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-system", Name: "api"},
+		Data:       map[string][]byte{SecretTokenKey: []byte("secret")},
+	}
+	authenticator := NewSecretTokenAuthenticator(kubefake.NewSimpleClientset(secret), "kube-system", "api")
+	handler := NewHandler(client, []Authenticator{authenticator})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		code   int
+	}{
+		{"missing token", http.MethodPost, "/trigger/default/web/morning", "", http.StatusUnauthorized},
+		{"invalid token", http.MethodPost, "/trigger/default/web/morning", "guess", http.StatusUnauthorized},
+		{"wrong method", http.MethodGet, "/trigger/default/web/morning", "secret", http.StatusMethodNotAllowed},
+		{"unknown cron", http.MethodPost, "/trigger/default/web/evening", "secret", http.StatusNotFound},
+		{"unknown cronhpa", http.MethodPost, "/trigger/default/api/morning", "secret", http.StatusNotFound},
+		{"triggered", http.MethodPost, "/trigger/default/web/morning", "secret", http.StatusAccepted},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s: expected %d, got %d: %s", test.name, test.code, w.Code, w.Body.String())
+		}
+	}
+
+	expected := `{"metadata":{"annotations":{"extensions.tkestack.io/trigger":"morning"}}}`
+	if patch != expected {
+		t.Errorf("expected patch %s, got %s", expected, patch)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tkestack.io/cron-hpa/pkg/access"
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller"
+	clientset "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// SecretTokenKey is the key of the bearer token in the Secret of
+// NewSecretTokenAuthenticator.
+const SecretTokenKey = "token"
+
+// Authenticator authenticates a bearer token for acting on a CronHPA.
+type Authenticator interface {
+	// Authenticate returns the user of token, or an *AuthError if the token
+	// is invalid or not allowed to act on the cronhpa.
+	Authenticate(token, namespace, name string) (string, error)
+}
+
+// AuthError is an error of authentication, with the HTTP status code to
+// respond with.
+type AuthError struct {
+	Code    int
+	Message string
+}
+
+func (e *AuthError) Error() string {
+	return e.Message
+}
+
+// bearerToken returns the bearer token of the Authorization header of r.
+func bearerToken(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(header, prefix))
+}
+
+// secretTokenAuthenticator accepts the token stored in a Secret. The Secret
+// is read on every request, so the token could be rotated without restarts.
+type secretTokenAuthenticator struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewSecretTokenAuthenticator creates an Authenticator accepting the token at
+// SecretTokenKey of the Secret namespace/name for all CronHPAs.
+func NewSecretTokenAuthenticator(client kubernetes.Interface, namespace, name string) Authenticator {
+	return &secretTokenAuthenticator{client: client, namespace: namespace, name: name}
+}
+
+func (a *secretTokenAuthenticator) Authenticate(token, namespace, name string) (string, error) {
+	secret, err := a.client.CoreV1().Secrets(a.namespace).Get(a.name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %v", a.namespace, a.name, err)
+	}
+	expected := secret.Data[SecretTokenKey]
+	if len(expected) == 0 || subtle.ConstantTimeCompare(expected, []byte(token)) != 1 {
+		return "", &AuthError{Code: http.StatusUnauthorized, Message: "invalid bearer token"}
+	}
+	return fmt.Sprintf("secret:%s/%s", a.namespace, a.name), nil
+}
+
+// tokenReviewAuthenticator accepts tokens of users, e.g. ServiceAccounts,
+// allowed to patch the cronhpa and to scale its target.
+type tokenReviewAuthenticator struct {
+	client        kubernetes.Interface
+	cronhpaClient clientset.Interface
+	access        *access.Reviewer
+}
+
+// NewTokenReviewAuthenticator creates an Authenticator that authenticates
+// tokens by TokenReviews, and authorizes their users by SubjectAccessReviews
+// of patching the cronhpa, the same as setting its annotations, and of
+// updating the scale of its target, as the controller scales it on their
+// behalf.
+func NewTokenReviewAuthenticator(client kubernetes.Interface, cronhpaClient clientset.Interface) Authenticator {
+	return &tokenReviewAuthenticator{client: client, cronhpaClient: cronhpaClient, access: access.NewReviewer(client)}
+}
+
+func (a *tokenReviewAuthenticator) Authenticate(token, namespace, name string) (string, error) {
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to review token: %v", err)
+	}
+	if !review.Status.Authenticated {
+		return "", &AuthError{Code: http.StatusUnauthorized, Message: "invalid bearer token"}
+	}
+	user := review.Status.User
+
+	if err := a.access.Review(user, &authorizationv1.ResourceAttributes{
+		Namespace: namespace,
+		Verb:      "patch",
+		Group:     cronhpacontroller.GroupName,
+		Resource:  "cronhpas",
+		Name:      name,
+	}); err != nil {
+		return "", toAuthError(err)
+	}
+	cronhpa, err := a.cronhpaClient.CronhpacontrollerV1().CronHPAs(namespace).Get(name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", &AuthError{Code: http.StatusNotFound, Message: err.Error()}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get cronhpa %s/%s: %v", namespace, name, err)
+	}
+	if err := a.access.ReviewScale(user, cronhpa); err != nil {
+		return "", toAuthError(err)
+	}
+	return user.Username, nil
+}
+
+// toAuthError returns a 403 *AuthError if err is an *access.DeniedError, or
+// otherwise err.
+func toAuthError(err error) error {
+	if denied, ok := err.(*access.DeniedError); ok {
+		return &AuthError{Code: http.StatusForbidden, Message: denied.Message}
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package v1
+
+// GetControllerName returns the name of the controller which should act on cronhpa.
+func GetControllerName(cronhpa *CronHPA) string {
+	if cronhpa.Spec.ControllerName == "" {
+		return DefaultControllerName
+	}
+	return cronhpa.Spec.ControllerName
+}
+
+// GetCronName returns the name of cron, which defaults to its schedule.
+func GetCronName(cron *Cron) string {
+	if cron.Name != "" {
+		return cron.Name
+	}
+	return cron.Schedule
+}
+
+// GetCronReplicas returns the target replicas of cron of cronhpa, which are
+// those of its profile if set, and false if the profile doesn't exist.
+func GetCronReplicas(cronhpa *CronHPA, cron *Cron) (int32, bool) {
+	if cron.Profile == "" {
+		return cron.TargetReplicas, true
+	}
+	profile, ok := cronhpa.Spec.Profiles[cron.Profile]
+	return profile.Replicas, ok
+}
+
+// GetFreezePolicy returns the policy of freeze, which defaults to Skip.
+func GetFreezePolicy(freeze *ScalingFreeze) FreezePolicy {
+	if freeze.Spec.Policy == "" {
+		return FreezeSkip
+	}
+	return freeze.Spec.Policy
+}
+
+// GetMissedSchedulePolicy returns the missed schedule policy of cronhpa, which
+// defaults to RunLatest.
+func GetMissedSchedulePolicy(cronhpa *CronHPA) MissedSchedulePolicy {
+	if cronhpa.Spec.MissedSchedulePolicy == "" {
+		return MissedScheduleRunLatest
+	}
+	return cronhpa.Spec.MissedSchedulePolicy
+}
@@ -48,6 +48,12 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&CronHPA{},
 		&CronHPAList{},
+		&CronHPAExecution{},
+		&CronHPAExecutionList{},
+		&ScalingFreeze{},
+		&ScalingFreezeList{},
+		&CronHPAPolicy{},
+		&CronHPAPolicyList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
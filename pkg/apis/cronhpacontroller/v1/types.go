@@ -19,6 +19,8 @@ package v1
 
 import (
 	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -45,13 +47,303 @@ type CronHPASpec struct {
 	ScaleTargetRef autoscalingv2.CrossVersionObjectReference `json:"scaleTargetRef" protobuf:"bytes,1,opt,name=scaleTargetRef"`
 
 	Crons []Cron `json:"crons" protobuf:"bytes,2,opt,name=crons"`
+
+	// The number of successful executions to retain.
+	// Defaults to 3.
+	// +optional
+	SuccessfulHistoryLimit *int32 `json:"successfulHistoryLimit,omitempty" protobuf:"varint,3,opt,name=successfulHistoryLimit"`
+
+	// The number of failed executions to retain.
+	// Defaults to 1.
+	// +optional
+	FailedHistoryLimit *int32 `json:"failedHistoryLimit,omitempty" protobuf:"varint,4,opt,name=failedHistoryLimit"`
+
+	// ControllerName is the name of the controller which should act on the
+	// CronHPA, so that several controllers could coexist. It is defaulted to
+	// DefaultControllerName by the admission webhook, and an empty value also
+	// means DefaultControllerName.
+	// +optional
+	ControllerName string `json:"controllerName,omitempty" protobuf:"bytes,5,opt,name=controllerName"`
+
+	// Every schedule fires after a delay in [0, maxJitterSeconds), which is
+	// derived from the namespace and name of the CronHPA, so that CronHPAs with
+	// the same schedule don't scale all at once. It should be less than the
+	// interval between schedules.
+	// +optional
+	MaxJitterSeconds *int32 `json:"maxJitterSeconds,omitempty" protobuf:"varint,6,opt,name=maxJitterSeconds"`
+
+	// QuotaPolicy decides what to do if a scale-up doesn't fit the remaining
+	// ResourceQuota of the namespace. Defaults to Ignore.
+	// +optional
+	QuotaPolicy QuotaPolicy `json:"quotaPolicy,omitempty" protobuf:"bytes,7,opt,name=quotaPolicy,casttype=QuotaPolicy"`
+
+	// RespectPDB makes scale-downs respect PodDisruptionBudgets matching pods
+	// of the target. The target replicas are raised to an integer minAvailable,
+	// and otherwise replicas are scaled down in steps of disruptions allowed.
+	// +optional
+	RespectPDB bool `json:"respectPDB,omitempty" protobuf:"varint,8,opt,name=respectPDB"`
+
+	// PodDeletionCost sets the pod deletion cost of the target's pods before
+	// scale-downs, so that pods with lower cost are removed first.
+	// +optional
+	PodDeletionCost *PodDeletionCost `json:"podDeletionCost,omitempty" protobuf:"bytes,9,opt,name=podDeletionCost"`
+
+	// Drain makes scale-downs drain surplus pods first. The pods are annotated
+	// with DrainingAnnotation, and replicas are reduced once all of them are
+	// annotated with DrainSafeAnnotation, or the drain times out.
+	// +optional
+	Drain *DrainPolicy `json:"drain,omitempty" protobuf:"bytes,10,opt,name=drain"`
+
+	// PreScale is a hook run before every scale, e.g. to warm caches before a
+	// scale-up.
+	// +optional
+	PreScale *ScaleHook `json:"preScale,omitempty" protobuf:"bytes,11,opt,name=preScale"`
+
+	// PostScale is a hook run after every scale, e.g. to notify a capacity
+	// planner of a scale-down.
+	// +optional
+	PostScale *ScaleHook `json:"postScale,omitempty" protobuf:"bytes,12,opt,name=postScale"`
+
+	// NotifyBefore is how long before every schedule to emit an
+	// UpcomingRescale event and notification, so that the schedule could be
+	// skipped by SkipNextAnnotation. Disabled if unset.
+	// +optional
+	NotifyBefore *metav1.Duration `json:"notifyBefore,omitempty" protobuf:"bytes,13,opt,name=notifyBefore"`
+
+	// Profiles are named replica settings, e.g. normal, peak and maintenance,
+	// which crons and overrides could refer to by name.
+	// +optional
+	Profiles map[string]Profile `json:"profiles,omitempty" protobuf:"bytes,14,rep,name=profiles"`
+
+	// MissedSchedulePolicy is how schedules which came due while scaling was
+	// paused are handled once it resumes. Defaults to RunLatest.
+	// +optional
+	MissedSchedulePolicy MissedSchedulePolicy `json:"missedSchedulePolicy,omitempty" protobuf:"bytes,15,opt,name=missedSchedulePolicy,casttype=MissedSchedulePolicy"`
+}
+
+// MissedSchedulePolicy is how a CronHPA handles schedules missed while paused.
+type MissedSchedulePolicy string
+
+const (
+	// MissedScheduleRunLatest runs the most recently missed schedule once,
+	// so that the target gets the replicas it should have now.
+	MissedScheduleRunLatest MissedSchedulePolicy = "RunLatest"
+	// MissedScheduleSkip drops all missed schedules, and waits for the next.
+	MissedScheduleSkip MissedSchedulePolicy = "Skip"
+)
+
+// Profile is a named replica setting of the target.
+type Profile struct {
+	// Replicas of the target in the profile.
+	Replicas int32 `json:"replicas" protobuf:"varint,1,opt,name=replicas"`
+}
+
+const (
+	// SkipNextAnnotation on a CronHPA skips the next occurrence of the cron it
+	// names, see Cron.Name. It is removed once the occurrence is skipped.
+	SkipNextAnnotation = "extensions.tkestack.io/skip-next"
+	// TriggerAnnotation on a CronHPA runs the cron it names once now. It is
+	// removed once accepted, and the run is reported by status.lastTrigger.
+	TriggerAnnotation = "extensions.tkestack.io/trigger"
+	// OverrideAnnotation on a CronHPA is a ReplicasOverride in JSON, which
+	// scales the target to its replicas until it expires. It is removed once
+	// accepted, and the override is reported by status.override.
+	OverrideAnnotation = "extensions.tkestack.io/override"
+)
+
+// ReplicasOverride is the value of OverrideAnnotation.
+type ReplicasOverride struct {
+	// Replicas to scale the target to. Ignored if Profile is set.
+	Replicas int32 `json:"replicas"`
+	// Profile forces the named profile of the CronHPA.
+	Profile string `json:"profile,omitempty"`
+	// Until is when the override expires, and the target returns to its schedules.
+	Until metav1.Time `json:"until"`
+	// Source of the override, Annotation by default, or API if set through the API.
+	Source ProfileSource `json:"source,omitempty"`
+}
+
+// ProfileSource is how a profile has been selected.
+type ProfileSource string
+
+const (
+	// ProfileSourceCron means selected by a cron.
+	ProfileSourceCron ProfileSource = "Cron"
+	// ProfileSourceTrigger means selected by a cron run by TriggerAnnotation.
+	ProfileSourceTrigger ProfileSource = "Trigger"
+	// ProfileSourceAnnotation means forced by OverrideAnnotation.
+	ProfileSourceAnnotation ProfileSource = "Annotation"
+	// ProfileSourceAPI means forced through the API of the controller.
+	ProfileSourceAPI ProfileSource = "API"
+)
+
+// ScaleHook is an HTTP call or a Job run around a scale. Exactly one of HTTP
+// and Job should be set.
+type ScaleHook struct {
+	// HTTP calls a service with a HookRequest.
+	// +optional
+	HTTP *HTTPHook `json:"http,omitempty" protobuf:"bytes,1,opt,name=http"`
+
+	// Job runs a Job created from the template.
+	// +optional
+	Job *batchv1beta1.JobTemplateSpec `json:"job,omitempty" protobuf:"bytes,2,opt,name=job"`
+
+	// Max seconds to wait for the hook, at least 1. Defaults to 30 for HTTP, up
+	// to 300, and 600 for Job, up to 3600.
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty" protobuf:"varint,3,opt,name=timeoutSeconds"`
+
+	// FailurePolicy decides what to do if the hook fails or times out.
+	// Defaults to Ignore.
+	// +optional
+	FailurePolicy HookFailurePolicy `json:"failurePolicy,omitempty" protobuf:"bytes,4,opt,name=failurePolicy,casttype=HookFailurePolicy"`
+}
+
+// HTTPHook is a service endpoint called with a POST of HookRequest in JSON,
+// which should respond 2xx on success.
+type HTTPHook struct {
+	// Scheme to connect with, HTTP or HTTPS. Defaults to HTTP.
+	// +optional
+	Scheme corev1.URIScheme `json:"scheme,omitempty" protobuf:"bytes,1,opt,name=scheme,casttype=k8s.io/api/core/v1.URIScheme"`
+
+	// Namespace of the service, which must be the namespace of the CronHPA
+	// if set, so that hooks can't call services of other namespaces.
+	// +optional
+	Namespace string `json:"namespace,omitempty" protobuf:"bytes,2,opt,name=namespace"`
+
+	// Name of the service.
+	Name string `json:"name" protobuf:"bytes,3,opt,name=name"`
+
+	// Port of the service.
+	Port int32 `json:"port" protobuf:"varint,4,opt,name=port"`
+
+	// Path of the endpoint.
+	// +optional
+	Path string `json:"path,omitempty" protobuf:"bytes,5,opt,name=path"`
+
+	// InsecureSkipTLSVerify skips verifying the certificate of the service
+	// with HTTPS, e.g. if it is signed by a private CA.
+	// +optional
+	InsecureSkipTLSVerify bool `json:"insecureSkipTLSVerify,omitempty" protobuf:"varint,6,opt,name=insecureSkipTLSVerify"`
+}
+
+// HookRequest is the body of an HTTP hook call. The same fields are passed to
+// Job hooks by environment variables, see the README.
+type HookRequest struct {
+	Phase           HookPhase   `json:"phase"`
+	Namespace       string      `json:"namespace"`
+	Name            string      `json:"name"`
+	Schedule        string      `json:"schedule"`
+	ScheduledTime   metav1.Time `json:"scheduledTime"`
+	CurrentReplicas int32       `json:"currentReplicas"`
+	TargetReplicas  int32       `json:"targetReplicas"`
+}
+
+// HookPhase is when a hook runs.
+type HookPhase string
+
+const (
+	PreScale  HookPhase = "PreScale"
+	PostScale HookPhase = "PostScale"
+)
+
+// HookFailurePolicy decides what to do if a hook fails.
+type HookFailurePolicy string
+
+const (
+	// HookFailurePolicyIgnore goes on as if the hook succeeded.
+	HookFailurePolicyIgnore HookFailurePolicy = "Ignore"
+	// HookFailurePolicyAbort skips the scale of a failed PreScale hook, and
+	// records the execution of a failed PostScale hook as failed.
+	HookFailurePolicyAbort HookFailurePolicy = "Abort"
+)
+
+const (
+	// DrainingAnnotation is set to "true" on pods to remove by a scale-down.
+	DrainingAnnotation = "extensions.tkestack.io/draining"
+	// DrainSafeAnnotation is set to "true" by draining pods once they are safe to remove.
+	DrainSafeAnnotation = "extensions.tkestack.io/drain-safe"
+)
+
+// DrainPolicy describes how to drain pods before scale-downs.
+type DrainPolicy struct {
+	// Max seconds to wait for draining pods to be safe to remove. Defaults to 3600.
+	// +optional
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty" protobuf:"varint,1,opt,name=timeoutSeconds"`
 }
 
+// PodDeletionCostSource is where pod deletion costs come from.
+type PodDeletionCostSource string
+
+const (
+	// PodDeletionCostAge makes the oldest pods cost the least.
+	PodDeletionCostAge PodDeletionCostSource = "Age"
+	// PodDeletionCostAnnotation reads the cost from an annotation of each pod.
+	PodDeletionCostAnnotation PodDeletionCostSource = "Annotation"
+	// PodDeletionCostHTTP gets the cost from an HTTP endpoint of each pod.
+	PodDeletionCostHTTP PodDeletionCostSource = "HTTP"
+)
+
+// PodDeletionCost describes how to get the deletion cost of pods, see
+// controller.kubernetes.io/pod-deletion-cost.
+type PodDeletionCost struct {
+	// Source of the cost, one of Age, Annotation and HTTP.
+	Source PodDeletionCostSource `json:"source" protobuf:"bytes,1,opt,name=source,casttype=PodDeletionCostSource"`
+
+	// The annotation of pods whose integer value is the cost. Required by the Annotation source.
+	// +optional
+	Annotation string `json:"annotation,omitempty" protobuf:"bytes,2,opt,name=annotation"`
+
+	// The endpoint of pods whose response body is the cost. Required by the HTTP source.
+	// +optional
+	HTTP *HTTPDeletionCost `json:"http,omitempty" protobuf:"bytes,3,opt,name=http"`
+}
+
+// HTTPDeletionCost is an HTTP endpoint of pods returning their deletion cost as an integer.
+type HTTPDeletionCost struct {
+	// Scheme to connect with, HTTP or HTTPS. Defaults to HTTP.
+	// +optional
+	Scheme corev1.URIScheme `json:"scheme,omitempty" protobuf:"bytes,1,opt,name=scheme,casttype=k8s.io/api/core/v1.URIScheme"`
+
+	// Port on the pod IP.
+	Port int32 `json:"port" protobuf:"varint,2,opt,name=port"`
+
+	// Path of the endpoint.
+	// +optional
+	Path string `json:"path,omitempty" protobuf:"bytes,3,opt,name=path"`
+}
+
+// QuotaPolicy decides what to do if a scale-up doesn't fit the remaining ResourceQuota.
+type QuotaPolicy string
+
+const (
+	// QuotaPolicyIgnore scales regardless of ResourceQuota.
+	QuotaPolicyIgnore QuotaPolicy = "Ignore"
+	// QuotaPolicyClamp scales up to the replicas which fit ResourceQuota.
+	QuotaPolicyClamp QuotaPolicy = "Clamp"
+	// QuotaPolicyRefuse doesn't scale if the target replicas don't fit ResourceQuota.
+	QuotaPolicyRefuse QuotaPolicy = "Refuse"
+)
+
+// DefaultControllerName is the name of the controller acting on CronHPAs
+// without a ControllerName.
+const DefaultControllerName = "extensions.tkestack.io/cron-hpa-controller"
+
 type Cron struct {
 	// The schedule in Cron format, see https://en.wikipedia.org/wiki/Cron.
 	Schedule string `json:"schedule" protobuf:"bytes,1,opt,name=schedule"`
 
 	TargetReplicas int32 `json:"targetReplicas" protobuf:"varint,2,opt,name=targetReplicas"`
+
+	// Name of the cron, unique in the CronHPA, which annotations refer to it
+	// by. Defaults to the schedule.
+	// +optional
+	Name string `json:"name,omitempty" protobuf:"bytes,3,opt,name=name"`
+
+	// Profile selects the named profile of the CronHPA, whose replicas take
+	// the place of TargetReplicas.
+	// +optional
+	Profile string `json:"profile,omitempty" protobuf:"bytes,4,opt,name=profile"`
 }
 
 // CronHPAStatus represents the current state of a CronHPA.
@@ -59,6 +351,225 @@ type CronHPAStatus struct {
 	// Information when was the last time the schedule was successfully scheduled.
 	// +optional
 	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty" protobuf:"bytes,2,opt,name=lastScheduleTime"`
+
+	// Conditions are the latest observations of the CronHPA's state.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	Conditions []CronHPACondition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,3,rep,name=conditions"`
+
+	// Drain is the state of the ongoing drain before a scale-down.
+	// +optional
+	Drain *DrainStatus `json:"drain,omitempty" protobuf:"bytes,4,opt,name=drain"`
+
+	// PreScaleHook is the state of the latest PreScale hook.
+	// +optional
+	PreScaleHook *HookStatus `json:"preScaleHook,omitempty" protobuf:"bytes,5,opt,name=preScaleHook"`
+
+	// PostScaleHook is the state of the latest PostScale hook.
+	// +optional
+	PostScaleHook *HookStatus `json:"postScaleHook,omitempty" protobuf:"bytes,6,opt,name=postScaleHook"`
+
+	// LastTrigger is the latest run of a cron by TriggerAnnotation.
+	// +optional
+	LastTrigger *TriggerStatus `json:"lastTrigger,omitempty" protobuf:"bytes,7,opt,name=lastTrigger"`
+
+	// LastSkip is the latest occurrence skipped by SkipNextAnnotation.
+	// +optional
+	LastSkip *SkipStatus `json:"lastSkip,omitempty" protobuf:"bytes,8,opt,name=lastSkip"`
+
+	// Override is the latest override by OverrideAnnotation.
+	// +optional
+	Override *OverrideStatus `json:"override,omitempty" protobuf:"bytes,9,opt,name=override"`
+
+	// ActiveProfile is the profile selected by the latest scale, if any.
+	// +optional
+	ActiveProfile *ProfileStatus `json:"activeProfile,omitempty" protobuf:"bytes,10,opt,name=activeProfile"`
+}
+
+// ProfileStatus is an active profile.
+type ProfileStatus struct {
+	// Name of the profile.
+	Name string `json:"name" protobuf:"bytes,1,opt,name=name"`
+
+	// Source is how the profile has been selected.
+	Source ProfileSource `json:"source" protobuf:"bytes,2,opt,name=source,casttype=ProfileSource"`
+
+	// Cron is the name of the cron which selected the profile, if any.
+	// +optional
+	Cron string `json:"cron,omitempty" protobuf:"bytes,3,opt,name=cron"`
+
+	// The time when the profile was activated.
+	Since metav1.Time `json:"since" protobuf:"bytes,4,opt,name=since"`
+}
+
+// ManualPhase is the phase of an action requested by an annotation.
+type ManualPhase string
+
+const (
+	// ManualPending means the action has been accepted, but not done yet.
+	ManualPending ManualPhase = "Pending"
+	// ManualSucceeded means the action has been done.
+	ManualSucceeded ManualPhase = "Succeeded"
+	// ManualFailed means the action is invalid or failed.
+	ManualFailed ManualPhase = "Failed"
+	// ManualActive means an override is in effect.
+	ManualActive ManualPhase = "Active"
+	// ManualExpired means an override has expired.
+	ManualExpired ManualPhase = "Expired"
+)
+
+// TriggerStatus is a run of a cron requested by TriggerAnnotation.
+type TriggerStatus struct {
+	// Name of the cron.
+	Cron string `json:"cron" protobuf:"bytes,1,opt,name=cron"`
+
+	// The time when the trigger was accepted.
+	TriggerTime metav1.Time `json:"triggerTime" protobuf:"bytes,2,opt,name=triggerTime"`
+
+	// The time when the run finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty" protobuf:"bytes,3,opt,name=completionTime"`
+
+	// Phase of the run, one of Pending, Succeeded and Failed.
+	Phase ManualPhase `json:"phase" protobuf:"bytes,4,opt,name=phase,casttype=ManualPhase"`
+
+	// A human readable message about the phase.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
+}
+
+// SkipStatus is an occurrence of a cron skipped by SkipNextAnnotation.
+type SkipStatus struct {
+	// Name of the cron.
+	Cron string `json:"cron" protobuf:"bytes,1,opt,name=cron"`
+
+	// The time when the skipped occurrence should have fired.
+	ScheduledTime metav1.Time `json:"scheduledTime" protobuf:"bytes,2,opt,name=scheduledTime"`
+
+	// The time when it was skipped.
+	SkipTime metav1.Time `json:"skipTime" protobuf:"bytes,3,opt,name=skipTime"`
+}
+
+// OverrideStatus is an override of replicas requested by OverrideAnnotation.
+type OverrideStatus struct {
+	ReplicasOverride `json:",inline" protobuf:"bytes,1,opt,name=replicasOverride"`
+
+	// The time when the override was accepted.
+	StartTime metav1.Time `json:"startTime" protobuf:"bytes,2,opt,name=startTime"`
+
+	// Replicas of the target before the override, which are restored on
+	// expiry unless a cron fires during the override.
+	// +optional
+	PreviousReplicas *int32 `json:"previousReplicas,omitempty" protobuf:"varint,3,opt,name=previousReplicas"`
+
+	// The active profile before the override, which is restored with
+	// PreviousReplicas.
+	// +optional
+	PreviousProfile *ProfileStatus `json:"previousProfile,omitempty" protobuf:"bytes,6,opt,name=previousProfile"`
+
+	// Phase of the override, one of Pending, Active, Expired and Failed.
+	Phase ManualPhase `json:"phase" protobuf:"bytes,4,opt,name=phase,casttype=ManualPhase"`
+
+	// A human readable message about the phase.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
+}
+
+// HookResult is the outcome of a hook.
+type HookResult string
+
+const (
+	HookRunning   HookResult = "Running"
+	HookSucceeded HookResult = "Succeeded"
+	HookFailed    HookResult = "Failed"
+	HookTimedOut  HookResult = "TimedOut"
+)
+
+// HookStatus is the state of a hook run for a schedule.
+type HookStatus struct {
+	// The time when the schedule should have fired.
+	ScheduledTime metav1.Time `json:"scheduledTime" protobuf:"bytes,1,opt,name=scheduledTime"`
+
+	// Replicas of the target before the scale.
+	CurrentReplicas int32 `json:"currentReplicas" protobuf:"varint,2,opt,name=currentReplicas"`
+
+	// Replicas of the target after the scale.
+	TargetReplicas int32 `json:"targetReplicas" protobuf:"varint,3,opt,name=targetReplicas"`
+
+	// The time when the hook started.
+	StartTime metav1.Time `json:"startTime" protobuf:"bytes,4,opt,name=startTime"`
+
+	// The time when the hook finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty" protobuf:"bytes,5,opt,name=completionTime"`
+
+	// Name of the Job of a Job hook.
+	// +optional
+	Job string `json:"job,omitempty" protobuf:"bytes,6,opt,name=job"`
+
+	// Result of the hook.
+	Result HookResult `json:"result" protobuf:"bytes,7,opt,name=result,casttype=HookResult"`
+
+	// A human readable message about the result.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,8,opt,name=message"`
+}
+
+// DrainStatus is the state of draining pods before a scale-down.
+type DrainStatus struct {
+	// The time when the schedule being drained for should have fired.
+	ScheduledTime metav1.Time `json:"scheduledTime" protobuf:"bytes,1,opt,name=scheduledTime"`
+
+	// Replicas to scale down to once drained.
+	TargetReplicas int32 `json:"targetReplicas" protobuf:"varint,2,opt,name=targetReplicas"`
+
+	// The time when draining started.
+	StartTime metav1.Time `json:"startTime" protobuf:"bytes,3,opt,name=startTime"`
+
+	// Names of the draining pods.
+	Pods []string `json:"pods" protobuf:"bytes,4,rep,name=pods"`
+
+	// The number of draining pods which are safe to remove.
+	SafePods int32 `json:"safePods" protobuf:"varint,5,opt,name=safePods"`
+}
+
+// CronHPAConditionType is the type of a CronHPACondition.
+type CronHPAConditionType string
+
+const (
+	// QuotaExceeded is true if the latest scale-up did not fit ResourceQuota,
+	// and was clamped or refused by QuotaPolicy.
+	QuotaExceeded CronHPAConditionType = "QuotaExceeded"
+	// PDBLimited is true if the latest scale-down has been raised or stepped
+	// to respect PodDisruptionBudgets.
+	PDBLimited CronHPAConditionType = "PDBLimited"
+	// Draining is true while pods are drained before a scale-down.
+	Draining CronHPAConditionType = "Draining"
+	// Paused is true while all scaling is paused by the controller's pause
+	// switch. It turns false when resumed, at which time schedules missed
+	// meanwhile are handled by MissedSchedulePolicy.
+	Paused CronHPAConditionType = "Paused"
+	// Conflict is true while other CronHPAs scale the same target, or HPAs of
+	// the target have bounds excluding replicas of the CronHPA's crons.
+	Conflict CronHPAConditionType = "Conflict"
+)
+
+// CronHPACondition describes the state of a CronHPA at a certain point.
+type CronHPACondition struct {
+	// Type of the condition.
+	Type CronHPAConditionType `json:"type" protobuf:"bytes,1,opt,name=type,casttype=CronHPAConditionType"`
+	// Status of the condition, one of True, False, Unknown.
+	Status corev1.ConditionStatus `json:"status" protobuf:"bytes,2,opt,name=status,casttype=k8s.io/api/core/v1.ConditionStatus"`
+	// The last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty" protobuf:"bytes,3,opt,name=lastTransitionTime"`
+	// The reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,4,opt,name=reason"`
+	// A human readable message indicating details about the transition.
+	// +optional
+	Message string `json:"message,omitempty" protobuf:"bytes,5,opt,name=message"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -69,3 +580,181 @@ type CronHPAList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []CronHPA `json:"items"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CronHPAExecution records one scale attempt made by a CronHPA, like a Job
+// created by a CronJob.
+type CronHPAExecution struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec describes the scheduled action which has been executed.
+	Spec CronHPAExecutionSpec `json:"spec,omitempty"`
+
+	// Status is the outcome of the execution.
+	Status CronHPAExecutionStatus `json:"status,omitempty"`
+}
+
+// CronHPAExecutionSpec is the scheduled action of a CronHPAExecution.
+type CronHPAExecutionSpec struct {
+	// scaleTargetRef points to the target resource which has been scaled
+	ScaleTargetRef autoscalingv2.CrossVersionObjectReference `json:"scaleTargetRef" protobuf:"bytes,1,opt,name=scaleTargetRef"`
+
+	// The schedule in Cron format which fired the execution.
+	Schedule string `json:"schedule" protobuf:"bytes,2,opt,name=schedule"`
+
+	// The time when the schedule should have fired.
+	ScheduledTime metav1.Time `json:"scheduledTime" protobuf:"bytes,3,opt,name=scheduledTime"`
+
+	TargetReplicas int32 `json:"targetReplicas" protobuf:"varint,4,opt,name=targetReplicas"`
+}
+
+// ExecutionResult is the outcome of a CronHPAExecution.
+type ExecutionResult string
+
+const (
+	// ExecutionSucceeded means the target has been scaled to the target replicas.
+	ExecutionSucceeded ExecutionResult = "Succeeded"
+	// ExecutionFailed means the target could not be scaled.
+	ExecutionFailed ExecutionResult = "Failed"
+)
+
+// CronHPAExecutionStatus represents the outcome of a CronHPAExecution.
+type CronHPAExecutionStatus struct {
+	// The time when the execution actually happened.
+	ExecutionTime metav1.Time `json:"executionTime" protobuf:"bytes,1,opt,name=executionTime"`
+
+	// Replicas of the target before the execution. It is zero if they could not be read.
+	OldReplicas int32 `json:"oldReplicas" protobuf:"varint,2,opt,name=oldReplicas"`
+
+	// Replicas of the target after the execution.
+	NewReplicas int32 `json:"newReplicas" protobuf:"varint,3,opt,name=newReplicas"`
+
+	Result ExecutionResult `json:"result" protobuf:"bytes,4,opt,name=result,casttype=ExecutionResult"`
+
+	// A human readable message indicating why the execution failed.
+	// +optional
+	Error string `json:"error,omitempty" protobuf:"bytes,5,opt,name=error"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CronHPAExecutionList is a collection of CronHPAExecution.
+type CronHPAExecutionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronHPAExecution `json:"items"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ScalingFreeze holds back scheduled scaling of CronHPAs in its scope during a
+// time range, e.g. a cluster upgrade or an incident freeze.
+type ScalingFreeze struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ScalingFreezeSpec `json:"spec"`
+}
+
+// ScalingFreezeSpec describes when and which CronHPAs are frozen.
+type ScalingFreezeSpec struct {
+	// Start is when the freeze begins.
+	Start metav1.Time `json:"start" protobuf:"bytes,1,opt,name=start"`
+
+	// End is when the freeze ends.
+	End metav1.Time `json:"end" protobuf:"bytes,2,opt,name=end"`
+
+	// Namespaces of the frozen CronHPAs. All namespaces if empty.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty" protobuf:"bytes,3,rep,name=namespaces"`
+
+	// Selector of labels of the frozen CronHPAs. All CronHPAs if unset.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty" protobuf:"bytes,4,opt,name=selector"`
+
+	// Policy of the scheduled actions blocked by the freeze. Defaults to Skip.
+	// +optional
+	Policy FreezePolicy `json:"policy,omitempty" protobuf:"bytes,5,opt,name=policy,casttype=FreezePolicy"`
+
+	// Reason of the freeze, reported in events of the blocked actions.
+	// +optional
+	Reason string `json:"reason,omitempty" protobuf:"bytes,6,opt,name=reason"`
+}
+
+// FreezePolicy is how a ScalingFreeze treats the scheduled actions it blocks.
+type FreezePolicy string
+
+const (
+	// FreezeSkip drops the blocked actions, as if they were skipped.
+	FreezeSkip FreezePolicy = "Skip"
+	// FreezeDefer keeps the blocked actions due, so that they are applied
+	// once the freeze ends.
+	FreezeDefer FreezePolicy = "Defer"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ScalingFreezeList is a collection of ScalingFreeze.
+type ScalingFreezeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScalingFreeze `json:"items"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CronHPAPolicy limits the CronHPAs in its namespace. CronHPAPolicies in the
+// namespace of the controller are the defaults of namespaces without any.
+// They are enforced on admission, and again on execution in case they change
+// after CronHPAs were admitted.
+type CronHPAPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CronHPAPolicySpec `json:"spec"`
+}
+
+// CronHPAPolicySpec describes the limits of CronHPAs. Unset limits are unlimited.
+type CronHPAPolicySpec struct {
+	// MaxReplicas is the max target replicas of each cron.
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty" protobuf:"varint,1,opt,name=maxReplicas"`
+
+	// MinReplicas is the min target replicas of each cron.
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty" protobuf:"varint,2,opt,name=minReplicas"`
+
+	// MinInterval is the min interval between fires of the crons of a CronHPA.
+	// +optional
+	MinInterval *metav1.Duration `json:"minInterval,omitempty" protobuf:"bytes,3,opt,name=minInterval"`
+
+	// AllowedSchedules are the schedules crons may have, e.g. "0 8 * * 1-5".
+	// +optional
+	AllowedSchedules []string `json:"allowedSchedules,omitempty" protobuf:"bytes,4,rep,name=allowedSchedules"`
+
+	// AllowedTargetKinds are the kinds of scale targets, e.g. Deployment.
+	// +optional
+	AllowedTargetKinds []string `json:"allowedTargetKinds,omitempty" protobuf:"bytes,5,rep,name=allowedTargetKinds"`
+
+	// MaxCrons is the max number of crons of a CronHPA.
+	// +optional
+	MaxCrons *int32 `json:"maxCrons,omitempty" protobuf:"varint,6,opt,name=maxCrons"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// CronHPAPolicyList is a collection of CronHPAPolicy.
+type CronHPAPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronHPAPolicy `json:"items"`
+}
@@ -28,6 +28,9 @@ import (
 type CronhpacontrollerV1Interface interface {
 	RESTClient() rest.Interface
 	CronHPAsGetter
+	CronHPAExecutionsGetter
+	CronHPAPoliciesGetter
+	ScalingFreezesGetter
 }
 
 // CronhpacontrollerV1Client is used to interact with features provided by the cronhpacontroller.extensions.tkestack.io group.
@@ -39,6 +42,18 @@ func (c *CronhpacontrollerV1Client) CronHPAs(namespace string) CronHPAInterface
 	return newCronHPAs(c, namespace)
 }
 
+func (c *CronhpacontrollerV1Client) CronHPAExecutions(namespace string) CronHPAExecutionInterface {
+	return newCronHPAExecutions(c, namespace)
+}
+
+func (c *CronhpacontrollerV1Client) CronHPAPolicies(namespace string) CronHPAPolicyInterface {
+	return newCronHPAPolicies(c, namespace)
+}
+
+func (c *CronhpacontrollerV1Client) ScalingFreezes() ScalingFreezeInterface {
+	return newScalingFreezes(c)
+}
+
 // NewForConfig creates a new CronhpacontrollerV1Client for the given config.
 func NewForConfig(c *rest.Config) (*CronhpacontrollerV1Client, error) {
 	config := *c
@@ -32,6 +32,18 @@ func (c *FakeCronhpacontrollerV1) CronHPAs(namespace string) v1.CronHPAInterface
 	return &FakeCronHPAs{c, namespace}
 }
 
+func (c *FakeCronhpacontrollerV1) CronHPAExecutions(namespace string) v1.CronHPAExecutionInterface {
+	return &FakeCronHPAExecutions{c, namespace}
+}
+
+func (c *FakeCronhpacontrollerV1) CronHPAPolicies(namespace string) v1.CronHPAPolicyInterface {
+	return &FakeCronHPAPolicies{c, namespace}
+}
+
+func (c *FakeCronhpacontrollerV1) ScalingFreezes() v1.ScalingFreezeInterface {
+	return &FakeScalingFreezes{c}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeCronhpacontrollerV1) RESTClient() rest.Interface {
@@ -19,3 +19,9 @@ limitations under the License.
 package v1
 
 type CronHPAExpansion interface{}
+
+type CronHPAExecutionExpansion interface{}
+
+type CronHPAPolicyExpansion interface{}
+
+type ScalingFreezeExpansion interface{}
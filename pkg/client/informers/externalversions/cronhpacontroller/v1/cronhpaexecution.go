@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	time "time"
+
+	cronhpacontrollerv1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	versioned "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+	internalinterfaces "tkestack.io/cron-hpa/pkg/client/informers/externalversions/internalinterfaces"
+	v1 "tkestack.io/cron-hpa/pkg/client/listers/cronhpacontroller/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// CronHPAExecutionInformer provides access to a shared informer and lister for
+// CronHPAExecutions.
+type CronHPAExecutionInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.CronHPAExecutionLister
+}
+
+type cronHPAExecutionInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewCronHPAExecutionInformer constructs a new informer for CronHPAExecution type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewCronHPAExecutionInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredCronHPAExecutionInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredCronHPAExecutionInformer constructs a new informer for CronHPAExecution type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredCronHPAExecutionInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CronhpacontrollerV1().CronHPAExecutions(namespace).List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CronhpacontrollerV1().CronHPAExecutions(namespace).Watch(options)
+			},
+		},
+		&cronhpacontrollerv1.CronHPAExecution{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *cronHPAExecutionInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredCronHPAExecutionInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *cronHPAExecutionInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&cronhpacontrollerv1.CronHPAExecution{}, f.defaultInformer)
+}
+
+func (f *cronHPAExecutionInformer) Lister() v1.CronHPAExecutionLister {
+	return v1.NewCronHPAExecutionLister(f.Informer().GetIndexer())
+}
@@ -26,6 +26,12 @@ import (
 type Interface interface {
 	// CronHPAs returns a CronHPAInformer.
 	CronHPAs() CronHPAInformer
+	// CronHPAExecutions returns a CronHPAExecutionInformer.
+	CronHPAExecutions() CronHPAExecutionInformer
+	// CronHPAPolicies returns a CronHPAPolicyInformer.
+	CronHPAPolicies() CronHPAPolicyInformer
+	// ScalingFreezes returns a ScalingFreezeInformer.
+	ScalingFreezes() ScalingFreezeInformer
 }
 
 type version struct {
@@ -43,3 +49,18 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) CronHPAs() CronHPAInformer {
 	return &cronHPAInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// CronHPAExecutions returns a CronHPAExecutionInformer.
+func (v *version) CronHPAExecutions() CronHPAExecutionInformer {
+	return &cronHPAExecutionInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// CronHPAPolicies returns a CronHPAPolicyInformer.
+func (v *version) CronHPAPolicies() CronHPAPolicyInformer {
+	return &cronHPAPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// ScalingFreezes returns a ScalingFreezeInformer.
+func (v *version) ScalingFreezes() ScalingFreezeInformer {
+	return &scalingFreezeInformer{factory: v.factory, tweakListOptions: v.tweakListOptions}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	time "time"
+
+	cronhpacontrollerv1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	versioned "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+	internalinterfaces "tkestack.io/cron-hpa/pkg/client/informers/externalversions/internalinterfaces"
+	v1 "tkestack.io/cron-hpa/pkg/client/listers/cronhpacontroller/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// ScalingFreezeInformer provides access to a shared informer and lister for
+// ScalingFreezes.
+type ScalingFreezeInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1.ScalingFreezeLister
+}
+
+type scalingFreezeInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+}
+
+// NewScalingFreezeInformer constructs a new informer for ScalingFreeze type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewScalingFreezeInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredScalingFreezeInformer(client, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredScalingFreezeInformer constructs a new informer for ScalingFreeze type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredScalingFreezeInformer(client versioned.Interface, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CronhpacontrollerV1().ScalingFreezes().List(options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.CronhpacontrollerV1().ScalingFreezes().Watch(options)
+			},
+		},
+		&cronhpacontrollerv1.ScalingFreeze{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *scalingFreezeInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredScalingFreezeInformer(client, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *scalingFreezeInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&cronhpacontrollerv1.ScalingFreeze{}, f.defaultInformer)
+}
+
+func (f *scalingFreezeInformer) Lister() v1.ScalingFreezeLister {
+	return v1.NewScalingFreezeLister(f.Informer().GetIndexer())
+}
@@ -55,6 +55,12 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=cronhpacontroller.extensions.tkestack.io, Version=v1
 	case v1.SchemeGroupVersion.WithResource("cronhpas"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Cronhpacontroller().V1().CronHPAs().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cronhpaexecutions"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cronhpacontroller().V1().CronHPAExecutions().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("cronhpapolicies"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cronhpacontroller().V1().CronHPAPolicies().Informer()}, nil
+	case v1.SchemeGroupVersion.WithResource("scalingfreezes"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Cronhpacontroller().V1().ScalingFreezes().Informer()}, nil
 
 	}
 
@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	v1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CronHPAExecutionLister helps list CronHPAExecutions.
+type CronHPAExecutionLister interface {
+	// List lists all CronHPAExecutions in the indexer.
+	List(selector labels.Selector) (ret []*v1.CronHPAExecution, err error)
+	// CronHPAExecutions returns an object that can list and get CronHPAExecutions.
+	CronHPAExecutions(namespace string) CronHPAExecutionNamespaceLister
+	CronHPAExecutionListerExpansion
+}
+
+// cronHPAExecutionLister implements the CronHPAExecutionLister interface.
+type cronHPAExecutionLister struct {
+	indexer cache.Indexer
+}
+
+// NewCronHPAExecutionLister returns a new CronHPAExecutionLister.
+func NewCronHPAExecutionLister(indexer cache.Indexer) CronHPAExecutionLister {
+	return &cronHPAExecutionLister{indexer: indexer}
+}
+
+// List lists all CronHPAExecutions in the indexer.
+func (s *cronHPAExecutionLister) List(selector labels.Selector) (ret []*v1.CronHPAExecution, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CronHPAExecution))
+	})
+	return ret, err
+}
+
+// CronHPAExecutions returns an object that can list and get CronHPAExecutions.
+func (s *cronHPAExecutionLister) CronHPAExecutions(namespace string) CronHPAExecutionNamespaceLister {
+	return cronHPAExecutionNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CronHPAExecutionNamespaceLister helps list and get CronHPAExecutions.
+type CronHPAExecutionNamespaceLister interface {
+	// List lists all CronHPAExecutions in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.CronHPAExecution, err error)
+	// Get retrieves the CronHPAExecution from the indexer for a given namespace and name.
+	Get(name string) (*v1.CronHPAExecution, error)
+	CronHPAExecutionNamespaceListerExpansion
+}
+
+// cronHPAExecutionNamespaceLister implements the CronHPAExecutionNamespaceLister
+// interface.
+type cronHPAExecutionNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CronHPAExecutions in the indexer for a given namespace.
+func (s cronHPAExecutionNamespaceLister) List(selector labels.Selector) (ret []*v1.CronHPAExecution, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CronHPAExecution))
+	})
+	return ret, err
+}
+
+// Get retrieves the CronHPAExecution from the indexer for a given namespace and name.
+func (s cronHPAExecutionNamespaceLister) Get(name string) (*v1.CronHPAExecution, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("cronhpaexecution"), name)
+	}
+	return obj.(*v1.CronHPAExecution), nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	v1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// CronHPAPolicyLister helps list CronHPAPolicies.
+type CronHPAPolicyLister interface {
+	// List lists all CronHPAPolicies in the indexer.
+	List(selector labels.Selector) (ret []*v1.CronHPAPolicy, err error)
+	// CronHPAPolicies returns an object that can list and get CronHPAPolicies.
+	CronHPAPolicies(namespace string) CronHPAPolicyNamespaceLister
+	CronHPAPolicyListerExpansion
+}
+
+// cronHPAPolicyLister implements the CronHPAPolicyLister interface.
+type cronHPAPolicyLister struct {
+	indexer cache.Indexer
+}
+
+// NewCronHPAPolicyLister returns a new CronHPAPolicyLister.
+func NewCronHPAPolicyLister(indexer cache.Indexer) CronHPAPolicyLister {
+	return &cronHPAPolicyLister{indexer: indexer}
+}
+
+// List lists all CronHPAPolicies in the indexer.
+func (s *cronHPAPolicyLister) List(selector labels.Selector) (ret []*v1.CronHPAPolicy, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CronHPAPolicy))
+	})
+	return ret, err
+}
+
+// CronHPAPolicies returns an object that can list and get CronHPAPolicies.
+func (s *cronHPAPolicyLister) CronHPAPolicies(namespace string) CronHPAPolicyNamespaceLister {
+	return cronHPAPolicyNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// CronHPAPolicyNamespaceLister helps list and get CronHPAPolicies.
+type CronHPAPolicyNamespaceLister interface {
+	// List lists all CronHPAPolicies in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1.CronHPAPolicy, err error)
+	// Get retrieves the CronHPAPolicy from the indexer for a given namespace and name.
+	Get(name string) (*v1.CronHPAPolicy, error)
+	CronHPAPolicyNamespaceListerExpansion
+}
+
+// cronHPAPolicyNamespaceLister implements the CronHPAPolicyNamespaceLister
+// interface.
+type cronHPAPolicyNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all CronHPAPolicies in the indexer for a given namespace.
+func (s cronHPAPolicyNamespaceLister) List(selector labels.Selector) (ret []*v1.CronHPAPolicy, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.CronHPAPolicy))
+	})
+	return ret, err
+}
+
+// Get retrieves the CronHPAPolicy from the indexer for a given namespace and name.
+func (s cronHPAPolicyNamespaceLister) Get(name string) (*v1.CronHPAPolicy, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("cronhpapolicy"), name)
+	}
+	return obj.(*v1.CronHPAPolicy), nil
+}
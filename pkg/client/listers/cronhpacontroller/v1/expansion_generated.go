@@ -25,3 +25,23 @@ type CronHPAListerExpansion interface{}
 // CronHPANamespaceListerExpansion allows custom methods to be added to
 // CronHPANamespaceLister.
 type CronHPANamespaceListerExpansion interface{}
+
+// CronHPAExecutionListerExpansion allows custom methods to be added to
+// CronHPAExecutionLister.
+type CronHPAExecutionListerExpansion interface{}
+
+// CronHPAExecutionNamespaceListerExpansion allows custom methods to be added to
+// CronHPAExecutionNamespaceLister.
+type CronHPAExecutionNamespaceListerExpansion interface{}
+
+// CronHPAPolicyListerExpansion allows custom methods to be added to
+// CronHPAPolicyLister.
+type CronHPAPolicyListerExpansion interface{}
+
+// CronHPAPolicyNamespaceListerExpansion allows custom methods to be added to
+// CronHPAPolicyNamespaceLister.
+type CronHPAPolicyNamespaceListerExpansion interface{}
+
+// ScalingFreezeListerExpansion allows custom methods to be added to
+// ScalingFreezeLister.
+type ScalingFreezeListerExpansion interface{}
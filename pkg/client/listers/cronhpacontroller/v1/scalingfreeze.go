@@ -0,0 +1,65 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	v1 "tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ScalingFreezeLister helps list ScalingFreezes.
+type ScalingFreezeLister interface {
+	// List lists all ScalingFreezes in the indexer.
+	List(selector labels.Selector) (ret []*v1.ScalingFreeze, err error)
+	// Get retrieves the ScalingFreeze from the index for a given name.
+	Get(name string) (*v1.ScalingFreeze, error)
+	ScalingFreezeListerExpansion
+}
+
+// scalingFreezeLister implements the ScalingFreezeLister interface.
+type scalingFreezeLister struct {
+	indexer cache.Indexer
+}
+
+// NewScalingFreezeLister returns a new ScalingFreezeLister.
+func NewScalingFreezeLister(indexer cache.Indexer) ScalingFreezeLister {
+	return &scalingFreezeLister{indexer: indexer}
+}
+
+// List lists all ScalingFreezes in the indexer.
+func (s *scalingFreezeLister) List(selector labels.Selector) (ret []*v1.ScalingFreeze, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1.ScalingFreeze))
+	})
+	return ret, err
+}
+
+// Get retrieves the ScalingFreeze from the index for a given name.
+func (s *scalingFreezeLister) Get(name string) (*v1.ScalingFreeze, error) {
+	obj, exists, err := s.indexer.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1.Resource("scalingfreeze"), name)
+	}
+	return obj.(*v1.ScalingFreeze), nil
+}
@@ -0,0 +1,236 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package cloudevents emits scale events of CronHPAs as CloudEvents 1.0 over
+// HTTP, see https://github.com/cloudevents/spec.
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller"
+	"tkestack.io/cron-hpa/pkg/notify"
+
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/klog"
+)
+
+// Mode is the HTTP content mode of CloudEvents.
+type Mode string
+
+const (
+	// ModeBinary sends attributes as ce-* headers, and data as the body.
+	ModeBinary Mode = "binary"
+	// ModeStructured sends the whole event as an application/cloudevents+json body.
+	ModeStructured Mode = "structured"
+)
+
+const (
+	specVersion = "1.0"
+	// typePrefix prefixes the type of all events, e.g. io.tkestack.cronhpa.scale.succeeded.
+	typePrefix = "io.tkestack.cronhpa."
+	// sendTimeout is the timeout of each send.
+	sendTimeout = 10 * time.Second
+	// maxRetries is the number of retries of a failed send.
+	maxRetries = 5
+)
+
+// initialBackoff is the delay before the first retry, doubled on each retry.
+var initialBackoff = time.Second
+
+// eventTypes are the CloudEvents types of notify events.
+var eventTypes = map[notify.EventType]string{
+	notify.ScheduleFired:  typePrefix + "schedule.fired",
+	notify.ScaleSucceeded: typePrefix + "scale.succeeded",
+	notify.ScaleSkipped:   typePrefix + "scale.skipped",
+	notify.ScaleFailed:    typePrefix + "scale.failed",
+	notify.ScaleUpcoming:  typePrefix + "scale.upcoming",
+	notify.ScaleDeferred:  typePrefix + "scale.deferred",
+}
+
+// Event is a CloudEvent in the structured mode.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Data      `json:"data"`
+}
+
+// Data is the data of all events.
+type Data struct {
+	Namespace       string    `json:"namespace"`
+	Name            string    `json:"name"`
+	Target          string    `json:"target"`
+	Schedule        string    `json:"schedule"`
+	ScheduledTime   time.Time `json:"scheduledTime"`
+	CurrentReplicas int32     `json:"currentReplicas"`
+	TargetReplicas  int32     `json:"targetReplicas"`
+	Message         string    `json:"message"`
+}
+
+// Emitter sends events to a sink in the background. It implements
+// notify.Notifier, and drops events beyond its queue size, so that it never
+// blocks the sync loop.
+type Emitter struct {
+	sink   string
+	mode   Mode
+	client *http.Client
+	queue  chan *Event
+}
+
+// NewEmitter creates an Emitter sending to sink in mode, with queueSize
+// pending events at most.
+func NewEmitter(sink string, mode Mode, queueSize int) (*Emitter, error) {
+	if mode != ModeBinary && mode != ModeStructured {
+		return nil, fmt.Errorf("unsupported mode %q", mode)
+	}
+	if queueSize < 1 {
+		return nil, fmt.Errorf("queue size must be positive")
+	}
+	return &Emitter{
+		sink:   sink,
+		mode:   mode,
+		client: &http.Client{Timeout: sendTimeout},
+		queue:  make(chan *Event, queueSize),
+	}, nil
+}
+
+// Notify queues event as a CloudEvent.
+func (e *Emitter) Notify(event *notify.Event) {
+	ce := &Event{
+		SpecVersion:     specVersion,
+		ID:              utilrand.String(16),
+		Source:          fmt.Sprintf("/apis/%s/v1/namespaces/%s/cronhpas/%s", cronhpacontroller.GroupName, event.Namespace, event.Name),
+		Type:            eventTypes[event.Type],
+		Subject:         event.Target,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data: Data{
+			Namespace:       event.Namespace,
+			Name:            event.Name,
+			Target:          event.Target,
+			Schedule:        event.Schedule,
+			ScheduledTime:   event.ScheduledTime,
+			CurrentReplicas: event.CurrentReplicas,
+			TargetReplicas:  event.TargetReplicas,
+			Message:         event.Message,
+		},
+	}
+	if ce.Type == "" {
+		ce.Type = typePrefix + strings.ToLower(string(event.Type))
+	}
+	select {
+	case e.queue <- ce:
+	default:
+		klog.Warningf("Dropped CloudEvent %s of %s/%s: queue is full", ce.Type, event.Namespace, event.Name)
+	}
+}
+
+// Run sends queued events until stopCh is closed.
+func (e *Emitter) Run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case event := <-e.queue:
+			if err := e.send(event, stopCh); err != nil {
+				klog.Errorf("Failed to send CloudEvent %s %s: %v", event.Type, event.ID, err)
+			}
+		}
+	}
+}
+
+// send sends event, and retries with exponential backoff on errors which may
+// be transient.
+func (e *Emitter) send(event *Event, stopCh <-chan struct{}) error {
+	backoff := initialBackoff
+	for retry := 0; ; retry++ {
+		retriable, err := e.post(event)
+		if err == nil || !retriable || retry >= maxRetries {
+			return err
+		}
+		klog.V(4).Infof("Retrying CloudEvent %s in %v: %v", event.ID, backoff, err)
+		select {
+		case <-stopCh:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends event once, and returns whether a failure is worth retrying.
+func (e *Emitter) post(event *Event) (bool, error) {
+	req, err := e.newRequest(event)
+	if err != nil {
+		return false, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	message, _ := ioutil.ReadAll(resp.Body)
+	err = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// newRequest returns the HTTP request of event in the mode of e.
+func (e *Emitter) newRequest(event *Event) (*http.Request, error) {
+	if e.mode == ModeStructured {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequest(http.MethodPost, e.sink, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/cloudevents+json; charset=UTF-8")
+		return req, nil
+	}
+
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.sink, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", event.DataContentType)
+	req.Header.Set("ce-specversion", event.SpecVersion)
+	req.Header.Set("ce-id", event.ID)
+	req.Header.Set("ce-source", event.Source)
+	req.Header.Set("ce-type", event.Type)
+	req.Header.Set("ce-subject", event.Subject)
+	req.Header.Set("ce-time", event.Time.UTC().Format(time.RFC3339Nano))
+	return req, nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cloudevents
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/notify"
+)
+
+func TestNewRequest(t *testing.T) {
+	for _, mode := range []Mode{ModeBinary, ModeStructured} {
+		e, err := NewEmitter("http://sink", mode, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.Notify(&notify.Event{
+			Type:            notify.ScaleSucceeded,
+			Namespace:       "default",
+			Name:            "web",
+			Target:          "Deployment/web",
+			CurrentReplicas: 2,
+			TargetReplicas:  10,
+			Time:            time.Now(),
+		})
+		event := <-e.queue
+		req, err := e.newRequest(event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+
+		var data Data
+		if mode == ModeBinary {
+			if req.Header.Get("ce-type") != "io.tkestack.cronhpa.scale.succeeded" || req.Header.Get("ce-specversion") != "1.0" {
+				t.Errorf("%s: unexpected headers %v", mode, req.Header)
+			}
+			json.Unmarshal(body, &data)
+		} else {
+			var structured Event
+			json.Unmarshal(body, &structured)
+			if structured.Type != "io.tkestack.cronhpa.scale.succeeded" || structured.Source != "/apis/extensions.tkestack.io/v1/namespaces/default/cronhpas/web" {
+				t.Errorf("%s: unexpected event %s", mode, body)
+			}
+			data = structured.Data
+		}
+		if data.CurrentReplicas != 2 || data.TargetReplicas != 10 || data.Target != "Deployment/web" {
+			t.Errorf("%s: unexpected data %+v", mode, data)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package conflict finds CronHPAs fighting over their scale targets, with each
+// other, or with HPAs whose bounds exclude their scheduled replicas.
+package conflict
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	cronhpainformers "tkestack.io/cron-hpa/pkg/client/informers/externalversions/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalinginformers "k8s.io/client-go/informers/autoscaling/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TargetIndex is the name of the index of CronHPAs and HPAs by scale targets.
+const TargetIndex = "scaleTarget"
+
+// Policy is how the admission webhook handles conflicts.
+type Policy string
+
+const (
+	// Warn allows conflicting CronHPAs, and logs the conflicts.
+	Warn Policy = "Warn"
+	// Reject denies conflicting CronHPAs.
+	Reject Policy = "Reject"
+)
+
+// Conflict is a CronHPA or an HPA conflicting with a CronHPA.
+type Conflict struct {
+	// Either CronHPA or HPA is set.
+	CronHPA *v1.CronHPA
+	HPA     *autoscalingv1.HorizontalPodAutoscaler
+	Message string
+}
+
+// Index looks up CronHPAs and HPAs by their scale targets.
+type Index struct {
+	cronhpas []cache.Indexer
+	hpas     []cache.Indexer
+	synced   []cache.InformerSynced
+}
+
+// NewIndex adds TargetIndex to cronhpaInformers and hpaInformers, which must
+// not have been started.
+func NewIndex(cronhpaInformers []cronhpainformers.CronHPAInformer, hpaInformers []autoscalinginformers.HorizontalPodAutoscalerInformer) (*Index, error) {
+	index := &Index{}
+	for _, informer := range cronhpaInformers {
+		if err := informer.Informer().AddIndexers(cache.Indexers{TargetIndex: cronhpaTargetIndexFunc}); err != nil {
+			return nil, err
+		}
+		index.cronhpas = append(index.cronhpas, informer.Informer().GetIndexer())
+		index.synced = append(index.synced, informer.Informer().HasSynced)
+	}
+	for _, informer := range hpaInformers {
+		if err := informer.Informer().AddIndexers(cache.Indexers{TargetIndex: hpaTargetIndexFunc}); err != nil {
+			return nil, err
+		}
+		index.hpas = append(index.hpas, informer.Informer().GetIndexer())
+		index.synced = append(index.synced, informer.Informer().HasSynced)
+	}
+	return index, nil
+}
+
+// HasSynced returns true if all indexed informers have synced.
+func (i *Index) HasSynced() bool {
+	for _, synced := range i.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns the CronHPAs and HPAs conflicting with cronhpa.
+func (i *Index) Find(cronhpa *v1.CronHPA) ([]Conflict, error) {
+	ref := cronhpa.Spec.ScaleTargetRef
+	key := targetKey(cronhpa.Namespace, ref.Kind, ref.Name)
+	var cronhpas []*v1.CronHPA
+	for _, indexer := range i.cronhpas {
+		objs, err := indexer.ByIndex(TargetIndex, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			cronhpas = append(cronhpas, obj.(*v1.CronHPA))
+		}
+	}
+	var hpas []*autoscalingv1.HorizontalPodAutoscaler
+	for _, indexer := range i.hpas {
+		objs, err := indexer.ByIndex(TargetIndex, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			hpas = append(hpas, obj.(*autoscalingv1.HorizontalPodAutoscaler))
+		}
+	}
+	return find(cronhpa, cronhpas, hpas), nil
+}
+
+// Describe joins the messages of conflicts.
+func Describe(conflicts []Conflict) string {
+	messages := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		messages = append(messages, conflict.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// find returns the conflicts of cronhpa among cronhpas and hpas scaling the
+// same target. Other CronHPAs always conflict, and HPAs conflict if any cron
+// of cronhpa scales outside of their replica bounds.
+func find(cronhpa *v1.CronHPA, cronhpas []*v1.CronHPA, hpas []*autoscalingv1.HorizontalPodAutoscaler) []Conflict {
+	ref := cronhpa.Spec.ScaleTargetRef
+	target := ref.Kind + "/" + ref.Name
+	var conflicts []Conflict
+	for _, other := range cronhpas {
+		if other.Name == cronhpa.Name || other.DeletionTimestamp != nil {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{
+			CronHPA: other,
+			Message: fmt.Sprintf("CronHPA %s also scales %s", other.Name, target),
+		})
+	}
+	for _, hpa := range hpas {
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		var excluded []string
+		for i := range cronhpa.Spec.Crons {
+			cron := &cronhpa.Spec.Crons[i]
+			replicas, ok := v1.GetCronReplicas(cronhpa, cron)
+			if ok && (replicas < minReplicas || replicas > hpa.Spec.MaxReplicas) {
+				excluded = append(excluded, fmt.Sprintf("%d of cron %s", replicas, v1.GetCronName(cron)))
+			}
+		}
+		if len(excluded) == 0 {
+			continue
+		}
+		conflicts = append(conflicts, Conflict{
+			HPA: hpa,
+			Message: fmt.Sprintf("HPA %s scales %s within [%d, %d] replicas, which excludes %s",
+				hpa.Name, target, minReplicas, hpa.Spec.MaxReplicas, strings.Join(excluded, ", ")),
+		})
+	}
+	sort.SliceStable(conflicts, func(i, j int) bool {
+		return conflicts[i].Message < conflicts[j].Message
+	})
+	return conflicts
+}
+
+// targetKey is the key of a scale target in TargetIndex. The API group is left
+// out, so that e.g. a Deployment referred to by extensions/v1beta1 and apps/v1
+// is the same target.
+func targetKey(namespace, kind, name string) string {
+	return namespace + "/" + kind + "/" + name
+}
+
+func cronhpaTargetIndexFunc(obj interface{}) ([]string, error) {
+	cronhpa, ok := obj.(*v1.CronHPA)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	ref := cronhpa.Spec.ScaleTargetRef
+	return []string{targetKey(cronhpa.Namespace, ref.Kind, ref.Name)}, nil
+}
+
+func hpaTargetIndexFunc(obj interface{}) ([]string, error) {
+	hpa, ok := obj.(*autoscalingv1.HorizontalPodAutoscaler)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %T", obj)
+	}
+	ref := hpa.Spec.ScaleTargetRef
+	return []string{targetKey(hpa.Namespace, ref.Kind, ref.Name)}, nil
+}
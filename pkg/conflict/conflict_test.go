@@ -0,0 +1,79 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package conflict
+
+import (
+	"testing"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFind(t *testing.T) {
+	ref := autoscalingv2.CrossVersionObjectReference{Kind: "Deployment", Name: "web", APIVersion: "apps/v1"}
+	newCronHPA := func(name string) *v1.CronHPA {
+		return &v1.CronHPA{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: v1.CronHPASpec{
+				ScaleTargetRef: ref,
+				Crons: []v1.Cron{
+					{Schedule: "0 8 * * *", TargetReplicas: 10},
+					{Schedule: "0 20 * * *", TargetReplicas: 2},
+				},
+			},
+		}
+	}
+	newHPA := func(name string, minReplicas *int32, maxReplicas int32) *autoscalingv1.HorizontalPodAutoscaler {
+		return &autoscalingv1.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+				MinReplicas: minReplicas,
+				MaxReplicas: maxReplicas,
+			},
+		}
+	}
+	three := int32(3)
+	cronhpa := newCronHPA("web")
+	deleted := newCronHPA("deleted")
+	deleted.DeletionTimestamp = &metav1.Time{}
+
+	tests := []struct {
+		name     string
+		cronhpas []*v1.CronHPA
+		hpas     []*autoscalingv1.HorizontalPodAutoscaler
+		expected string
+	}{
+		{"itself", []*v1.CronHPA{cronhpa}, nil, ""},
+		{"deleted", []*v1.CronHPA{cronhpa, deleted}, nil, ""},
+		{"other cronhpa", []*v1.CronHPA{cronhpa, newCronHPA("other")}, nil,
+			"CronHPA other also scales Deployment/web"},
+		{"hpa within bounds", nil, []*autoscalingv1.HorizontalPodAutoscaler{newHPA("web", nil, 10)}, ""},
+		{"hpa below max", nil, []*autoscalingv1.HorizontalPodAutoscaler{newHPA("web", nil, 5)},
+			"HPA web scales Deployment/web within [1, 5] replicas, which excludes 10 of cron 0 8 * * *"},
+		{"hpa above min", nil, []*autoscalingv1.HorizontalPodAutoscaler{newHPA("web", &three, 5)},
+			"HPA web scales Deployment/web within [3, 5] replicas, which excludes 10 of cron 0 8 * * *, 2 of cron 0 20 * * *"},
+	}
+	for _, test := range tests {
+		if message := Describe(find(cronhpa, test.cronhpas, test.hpas)); message != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, message)
+		}
+	}
+}
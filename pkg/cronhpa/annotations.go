@@ -0,0 +1,314 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/notify"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// manualKind is the kind of a scale action requested by an annotation.
+type manualKind string
+
+const (
+	// manualTrigger runs a cron by TriggerAnnotation.
+	manualTrigger manualKind = "Trigger"
+	// manualOverride applies an override by OverrideAnnotation.
+	manualOverride manualKind = "Override"
+	// manualRestore restores the replicas before an expired override.
+	manualRestore manualKind = "Restore"
+)
+
+// overrideCronName is the cron name of override actions in executions and
+// notifications.
+const overrideCronName = "override"
+
+// findCron returns the cron of cronhpa named name, or nil if not found.
+func findCron(cronhpa *v1.CronHPA, name string) *v1.Cron {
+	for i := range cronhpa.Spec.Crons {
+		if v1.GetCronName(&cronhpa.Spec.Crons[i]) == name {
+			return &cronhpa.Spec.Crons[i]
+		}
+	}
+	return nil
+}
+
+// acceptAnnotations moves the requests of TriggerAnnotation and
+// OverrideAnnotation of cronhpa into its status as pending, and removes the
+// annotations. It returns false if there are none.
+func (c *Controller) acceptAnnotations(cronhpa *v1.CronHPA, now time.Time) bool {
+	name, trigger := cronhpa.Annotations[v1.TriggerAnnotation]
+	value, override := cronhpa.Annotations[v1.OverrideAnnotation]
+	if !trigger && !override {
+		return false
+	}
+
+	if trigger {
+		delete(cronhpa.Annotations, v1.TriggerAnnotation)
+		status := &v1.TriggerStatus{
+			Cron:        name,
+			TriggerTime: metav1.Time{Time: now},
+			Phase:       v1.ManualPending,
+		}
+		if cron := findCron(cronhpa, name); cron == nil {
+			status.Phase = v1.ManualFailed
+			status.Message = fmt.Sprintf("No cron named %q", name)
+			status.CompletionTime = &metav1.Time{Time: now}
+			c.recorder.Event(cronhpa, corev1.EventTypeWarning, "FailedTrigger", status.Message)
+		} else if _, ok := v1.GetCronReplicas(cronhpa, cron); !ok {
+			status.Phase = v1.ManualFailed
+			status.Message = fmt.Sprintf("No profile named %q of cron %s", cron.Profile, name)
+			status.CompletionTime = &metav1.Time{Time: now}
+			c.recorder.Event(cronhpa, corev1.EventTypeWarning, "FailedTrigger", status.Message)
+		} else {
+			c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "Triggered", "Triggered cron %s", name)
+		}
+		cronhpa.Status.LastTrigger = status
+	}
+
+	if override {
+		delete(cronhpa.Annotations, v1.OverrideAnnotation)
+		status := &v1.OverrideStatus{
+			StartTime: metav1.Time{Time: now},
+			Phase:     v1.ManualPending,
+		}
+		parsed, err := parseOverride(cronhpa, value, now)
+		status.ReplicasOverride = parsed
+		if err != nil {
+			status.Phase = v1.ManualFailed
+			status.Message = fmt.Sprintf("Invalid %s: %v", v1.OverrideAnnotation, err)
+			c.recorder.Event(cronhpa, corev1.EventTypeWarning, "FailedOverride", status.Message)
+		} else {
+			if old := cronhpa.Status.Override; old != nil && old.Phase == v1.ManualActive {
+				// Restore the replicas before the first override on expiry
+				status.PreviousReplicas = old.PreviousReplicas
+				status.PreviousProfile = old.PreviousProfile
+			}
+			c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "Overridden", "Overriding replicas to %d until %s",
+				status.Replicas, status.Until.Format(time.RFC3339))
+		}
+		cronhpa.Status.Override = status
+	}
+
+	if _, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Update(cronhpa); err != nil {
+		klog.Errorf("Failed to accept annotations of cronhpa %s: %v", getCronHPAFullName(cronhpa), err)
+	}
+	return true
+}
+
+// parseOverride parses value of OverrideAnnotation of cronhpa, where the
+// replicas of a profile are resolved.
+func parseOverride(cronhpa *v1.CronHPA, value string, now time.Time) (v1.ReplicasOverride, error) {
+	override := v1.ReplicasOverride{}
+	if err := json.Unmarshal([]byte(value), &override); err != nil {
+		return override, err
+	}
+	if override.Source == "" {
+		override.Source = v1.ProfileSourceAnnotation
+	}
+	if override.Profile != "" {
+		profile, ok := cronhpa.Spec.Profiles[override.Profile]
+		if !ok {
+			return override, fmt.Errorf("no profile named %q", override.Profile)
+		}
+		override.Replicas = profile.Replicas
+	}
+	if override.Replicas < 0 {
+		return override, fmt.Errorf("negative replicas")
+	}
+	if !override.Until.After(now) {
+		return override, fmt.Errorf("expired at %s", override.Until.Format(time.RFC3339))
+	}
+	return override, nil
+}
+
+// planManual returns the action of a pending trigger or override of cronhpa
+// without its target scale, or nil if there is none.
+func (c *Controller) planManual(cronhpa *v1.CronHPA, now time.Time) *scaleAction {
+	if status := cronhpa.Status.LastTrigger; status != nil && status.Phase == v1.ManualPending {
+		if cron := findCron(cronhpa, status.Cron); cron != nil {
+			if replicas, ok := v1.GetCronReplicas(cronhpa, cron); ok {
+				action := &scaleAction{cronhpa: cronhpa, cron: *cron, manual: manualTrigger, scheduledTime: status.TriggerTime.Time}
+				action.cron.TargetReplicas = replicas
+				return action
+			}
+		}
+		// The cron or its profile has been removed since accepted
+		status.Phase = v1.ManualFailed
+		status.Message = fmt.Sprintf("No cron named %q with a valid profile", status.Cron)
+		status.CompletionTime = &metav1.Time{Time: now}
+		c.updateStatus(cronhpa)
+		return nil
+	}
+	if status := cronhpa.Status.Override; status != nil && status.Phase == v1.ManualPending {
+		return &scaleAction{
+			cronhpa:       cronhpa,
+			cron:          v1.Cron{Name: overrideCronName, TargetReplicas: status.Replicas},
+			manual:        manualOverride,
+			scheduledTime: status.StartTime.Time,
+		}
+	}
+	return nil
+}
+
+// planOverrideExpiry returns the action restoring the replicas before an
+// expired override of cronhpa, or nil if there is none.
+func planOverrideExpiry(cronhpa *v1.CronHPA, now time.Time) *scaleAction {
+	if !isOverrideExpired(cronhpa, now) {
+		return nil
+	}
+	status := cronhpa.Status.Override
+	replicas := status.Replicas
+	if status.PreviousReplicas != nil {
+		replicas = *status.PreviousReplicas
+	}
+	return &scaleAction{
+		cronhpa:       cronhpa,
+		cron:          v1.Cron{Name: overrideCronName, TargetReplicas: replicas},
+		manual:        manualRestore,
+		scheduledTime: status.Until.Time,
+	}
+}
+
+// isOverrideActive returns true if an override of cronhpa is in effect, where
+// its schedules are suppressed.
+func isOverrideActive(cronhpa *v1.CronHPA, now time.Time) bool {
+	status := cronhpa.Status.Override
+	return status != nil && status.Phase == v1.ManualActive && now.Before(status.Until.Time)
+}
+
+// isOverrideExpired returns true if an override of cronhpa has expired, but
+// the target hasn't returned to its schedules yet.
+func isOverrideExpired(cronhpa *v1.CronHPA, now time.Time) bool {
+	status := cronhpa.Status.Override
+	return status != nil && status.Phase == v1.ManualActive && !now.Before(status.Until.Time)
+}
+
+// expireOverride marks the override of cronhpa expired.
+func expireOverride(cronhpa *v1.CronHPA, message string) {
+	cronhpa.Status.Override.Phase = v1.ManualExpired
+	cronhpa.Status.Override.Message = message
+}
+
+// finishManual records the result of a manual action in the status of its
+// cronhpa. scaleErr is non-nil if a hook aborted it.
+func (c *Controller) finishManual(action *scaleAction, oldReplicas, replicas int32, scaleErr error) {
+	cronhpa := action.cronhpa
+	now := metav1.Now()
+	switch action.manual {
+	case manualTrigger:
+		status := cronhpa.Status.LastTrigger
+		if status == nil || !status.TriggerTime.Time.Equal(action.scheduledTime) {
+			// Superseded by a later trigger
+			return
+		}
+		status.CompletionTime = &now
+		if scaleErr != nil {
+			status.Phase = v1.ManualFailed
+			status.Message = scaleErr.Error()
+		} else {
+			status.Phase = v1.ManualSucceeded
+			status.Message = fmt.Sprintf("Scaled from %d to %d replicas", oldReplicas, replicas)
+			cronhpa.Status.ActiveProfile = newProfileStatus(&action.cron, v1.ProfileSourceTrigger)
+		}
+	case manualOverride:
+		status := cronhpa.Status.Override
+		if status == nil || !status.StartTime.Time.Equal(action.scheduledTime) {
+			return
+		}
+		if scaleErr != nil {
+			status.Phase = v1.ManualFailed
+			status.Message = scaleErr.Error()
+			return
+		}
+		status.Phase = v1.ManualActive
+		if status.PreviousReplicas == nil {
+			status.PreviousReplicas = &oldReplicas
+			status.PreviousProfile = cronhpa.Status.ActiveProfile
+		}
+		cronhpa.Status.ActiveProfile = nil
+		if status.Profile != "" {
+			cronhpa.Status.ActiveProfile = &v1.ProfileStatus{Name: status.Profile, Source: status.Source, Since: now}
+		}
+		status.Message = fmt.Sprintf("Scaled from %d to %d replicas until %s", oldReplicas, replicas, status.Until.Format(time.RFC3339))
+	case manualRestore:
+		if cronhpa.Status.Override == nil {
+			return
+		}
+		if scaleErr != nil {
+			expireOverride(cronhpa, scaleErr.Error())
+		} else {
+			expireOverride(cronhpa, fmt.Sprintf("Restored from %d to %d replicas", oldReplicas, replicas))
+			cronhpa.Status.ActiveProfile = cronhpa.Status.Override.PreviousProfile
+		}
+	}
+}
+
+// isSkipNext returns true if the SkipNextAnnotation of cronhpa names cron.
+func isSkipNext(cronhpa *v1.CronHPA, cron *v1.Cron) bool {
+	name, ok := cronhpa.Annotations[v1.SkipNextAnnotation]
+	return ok && name == v1.GetCronName(cron)
+}
+
+// skipNext skips the due occurrence of cron at scheduledTime if the
+// SkipNextAnnotation names it. It returns true if skipped, where the
+// annotation is removed and the occurrence is done.
+func (c *Controller) skipNext(cronhpa *v1.CronHPA, cron v1.Cron, scheduledTime, now time.Time) bool {
+	if !isSkipNext(cronhpa, &cron) {
+		return false
+	}
+	message := fmt.Sprintf("Skipped scaling to %d replicas by cron %s scheduled at %s, as annotated by %s",
+		cron.TargetReplicas, v1.GetCronName(&cron), scheduledTime.Format(time.RFC3339), v1.SkipNextAnnotation)
+	klog.Infof("%s: %s", getCronHPAFullName(cronhpa), message)
+	c.recorder.Event(cronhpa, corev1.EventTypeNormal, "SkippedRescale", message)
+	c.notify(notify.ScaleSkipped, cronhpa, cron, scheduledTime, 0, cron.TargetReplicas, message)
+
+	delete(cronhpa.Annotations, v1.SkipNextAnnotation)
+	cronhpa.Status.LastScheduleTime = &metav1.Time{Time: now}
+	cronhpa.Status.LastSkip = &v1.SkipStatus{
+		Cron:          v1.GetCronName(&cron),
+		ScheduledTime: metav1.Time{Time: scheduledTime},
+		SkipTime:      metav1.Time{Time: now},
+	}
+	if _, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Update(cronhpa); err != nil {
+		klog.Errorf("Failed to update cronhpa %s after skipping: %v", getCronHPAFullName(cronhpa), err)
+	}
+	return true
+}
+
+// newProfileStatus returns the status of the profile selected by cron from
+// source, or nil if cron selects no profile.
+func newProfileStatus(cron *v1.Cron, source v1.ProfileSource) *v1.ProfileStatus {
+	if cron.Profile == "" {
+		return nil
+	}
+	return &v1.ProfileStatus{
+		Name:   cron.Profile,
+		Source: source,
+		Cron:   v1.GetCronName(cron),
+		Since:  metav1.Now(),
+	}
+}
@@ -0,0 +1,122 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOverrideExpiry(t *testing.T) {
+	now := time.Now()
+	previous := int32(3)
+	cronhpa := &v1.CronHPA{Status: v1.CronHPAStatus{Override: &v1.OverrideStatus{
+		ReplicasOverride: v1.ReplicasOverride{Replicas: 10, Until: metav1.Time{Time: now.Add(time.Hour)}},
+		PreviousReplicas: &previous,
+		Phase:            v1.ManualActive,
+	}}}
+	if !isOverrideActive(cronhpa, now) || planOverrideExpiry(cronhpa, now) != nil {
+		t.Errorf("expected an active override")
+	}
+
+	later := now.Add(2 * time.Hour)
+	if isOverrideActive(cronhpa, later) {
+		t.Errorf("expected an expired override")
+	}
+	action := planOverrideExpiry(cronhpa, later)
+	if action == nil || action.manual != manualRestore || action.cron.TargetReplicas != previous {
+		t.Errorf("expected restoring %d replicas, got %+v", previous, action)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	tc := newTestController(t, newDeployment("web", 2))
+	cronhpa := newTestCronHPA("web", 0, 90*time.Second)
+	cronhpa.Spec.Profiles = map[string]v1.Profile{"normal": {Replicas: 3}, "peak": {Replicas: 6}}
+	cronhpa.Spec.Crons[0].Name = "daily"
+	cronhpa.Spec.Crons[0].Profile = "normal"
+	tc.addCronHPA(t, cronhpa)
+	sync := func(replicas int32) *v1.CronHPA {
+		t.Helper()
+		tc.syncAll(context.TODO(), 1)
+		if actual := tc.getReplicas(t, "web"); actual != replicas {
+			t.Errorf("expected %d replicas, got %d", replicas, actual)
+		}
+		return tc.getCronHPA(t, "web")
+	}
+	expectProfile := func(cronhpa *v1.CronHPA, name string, source v1.ProfileSource) {
+		t.Helper()
+		if profile := cronhpa.Status.ActiveProfile; profile == nil || profile.Name != name || profile.Source != source {
+			t.Errorf("expected active profile %s from %s, got %+v", name, source, profile)
+		}
+	}
+	override := func(cronhpa *v1.CronHPA, profile string) {
+		t.Helper()
+		value, _ := json.Marshal(v1.ReplicasOverride{Profile: profile, Until: metav1.Time{Time: time.Now().Add(time.Hour)}})
+		cronhpa.Annotations = map[string]string{v1.OverrideAnnotation: string(value)}
+		tc.updateCronHPA(t, cronhpa)
+	}
+
+	// A cron selects its profile
+	cronhpa = sync(3)
+	expectProfile(cronhpa, "normal", v1.ProfileSourceCron)
+
+	// An override is accepted first, and applied on the next sync
+	override(cronhpa, "peak")
+	cronhpa = sync(3)
+	if _, ok := cronhpa.Annotations[v1.OverrideAnnotation]; ok {
+		t.Errorf("expected %s removed, got %v", v1.OverrideAnnotation, cronhpa.Annotations)
+	}
+	if status := cronhpa.Status.Override; status == nil || status.Phase != v1.ManualPending || status.Replicas != 6 {
+		t.Fatalf("expected a pending override to 6 replicas, got %+v", status)
+	}
+	cronhpa = sync(6)
+	if status := cronhpa.Status.Override; status.Phase != v1.ManualActive || status.PreviousReplicas == nil ||
+		*status.PreviousReplicas != 3 || status.PreviousProfile == nil || status.PreviousProfile.Name != "normal" {
+		t.Errorf("expected an active override from 3 replicas of normal, got %+v", status)
+	}
+	expectProfile(cronhpa, "peak", v1.ProfileSourceAnnotation)
+
+	// The expired override restores the previous profile
+	cronhpa.Status.Override.Until = metav1.Time{Time: time.Now().Add(-time.Second)}
+	tc.updateCronHPA(t, cronhpa)
+	cronhpa = sync(3)
+	if phase := cronhpa.Status.Override.Phase; phase != v1.ManualExpired {
+		t.Errorf("expected an expired override, got %s", phase)
+	}
+	expectProfile(cronhpa, "normal", v1.ProfileSourceCron)
+
+	// Unknown profiles fail the override
+	tc.events()
+	override(cronhpa, "missing")
+	cronhpa = sync(3)
+	if status := cronhpa.Status.Override; status.Phase != v1.ManualFailed {
+		t.Errorf("expected a failed override, got %+v", status)
+	}
+	if events := tc.events(); len(events) != 1 || events[0] != "FailedOverride" {
+		t.Errorf("expected a FailedOverride event, got %v", events)
+	}
+	cronhpa = sync(3)
+	expectProfile(cronhpa, "normal", v1.ProfileSourceCron)
+}
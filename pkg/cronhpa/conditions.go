@@ -0,0 +1,51 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getCondition returns the condition of conditionType in status, or nil if absent.
+func getCondition(status *v1.CronHPAStatus, conditionType v1.CronHPAConditionType) *v1.CronHPACondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// setCondition sets the condition of conditionType in status. LastTransitionTime
+// is only updated if the status of the condition changes.
+func setCondition(status *v1.CronHPAStatus, conditionType v1.CronHPAConditionType, conditionStatus corev1.ConditionStatus, reason, message string) {
+	condition := getCondition(status, conditionType)
+	if condition == nil {
+		status.Conditions = append(status.Conditions, v1.CronHPACondition{Type: conditionType})
+		condition = &status.Conditions[len(status.Conditions)-1]
+	}
+	if condition.Status != conditionStatus {
+		condition.Status = conditionStatus
+		condition.LastTransitionTime = metav1.Now()
+	}
+	condition.Reason = reason
+	condition.Message = message
+}
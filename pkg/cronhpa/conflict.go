@@ -0,0 +1,67 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/conflict"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+)
+
+// syncConflict sets the Conflict condition of cronhpa by the CronHPAs and HPAs
+// scaling its target. Conflicts are warned of by events on cronhpa and the
+// HPAs, and don't stop scaling. It returns true if the status of cronhpa has
+// been updated, so it should not be planned on this sync. If the update fails,
+// cronhpa is planned as usual, and the condition is retried on the next sync.
+func (c *Controller) syncConflict(cronhpa *v1.CronHPA) bool {
+	conflicts, err := c.conflicts.Find(cronhpa)
+	if err != nil {
+		klog.Errorf("Failed to find conflicts of cronhpa %s: %v", getCronHPAFullName(cronhpa), err)
+		return false
+	}
+	condition := getCondition(&cronhpa.Status, v1.Conflict)
+	wasConflicting := condition != nil && condition.Status == corev1.ConditionTrue
+	if len(conflicts) == 0 {
+		if !wasConflicting {
+			return false
+		}
+		klog.Infof("%s: conflicts resolved", getCronHPAFullName(cronhpa))
+		setCondition(&cronhpa.Status, v1.Conflict, corev1.ConditionFalse, "NoConflict", "No conflicting CronHPA or HPA")
+		return c.updateStatus(cronhpa)
+	}
+
+	message := conflict.Describe(conflicts)
+	if wasConflicting && condition.Message == message {
+		return false
+	}
+	klog.Warningf("%s: %s", getCronHPAFullName(cronhpa), message)
+	c.recorder.Event(cronhpa, corev1.EventTypeWarning, "Conflict", message)
+	for _, found := range conflicts {
+		// Conflicting CronHPAs set their own conditions
+		if found.HPA != nil {
+			c.recorder.Event(found.HPA, corev1.EventTypeWarning, "CronHPAConflict",
+				fmt.Sprintf("CronHPA %s: %s", cronhpa.Name, found.Message))
+		}
+	}
+	setCondition(&cronhpa.Status, v1.Conflict, corev1.ConditionTrue, "Conflicting", message)
+	return c.updateStatus(cronhpa)
+}
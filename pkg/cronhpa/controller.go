@@ -18,20 +18,33 @@
 package cronhpa
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
 	clientset "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
 	cronhpascheme "tkestack.io/cron-hpa/pkg/client/clientset/versioned/scheme"
+	cronhpainformers "tkestack.io/cron-hpa/pkg/client/informers/externalversions/cronhpacontroller/v1"
+	cronhpalisters "tkestack.io/cron-hpa/pkg/client/listers/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/conflict"
+	"tkestack.io/cron-hpa/pkg/metrics"
+	"tkestack.io/cron-hpa/pkg/notify"
+	"tkestack.io/cron-hpa/pkg/pause"
+	"tkestack.io/cron-hpa/pkg/sharding"
 
 	cronutil "github.com/robfig/cron"
 	autoscalingv1 "k8s.io/api/autoscaling/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	cacheddiscovery "k8s.io/client-go/discovery/cached"
 	"k8s.io/client-go/dynamic"
@@ -40,12 +53,21 @@ import (
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/restmapper"
 	scaleclient "k8s.io/client-go/scale"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
 	controllerpkg "k8s.io/kubernetes/pkg/controller"
 )
 
-const controllerAgentName = "cronhpa-controller"
+const (
+	controllerAgentName = "cronhpa-controller"
+
+	// syncPeriod is the interval between syncs of all cronhpas.
+	syncPeriod = 10 * time.Second
+	// syncStalledTimeout is how long the sync loop may make no progress before
+	// the controller is considered unhealthy.
+	syncStalledTimeout = 3 * time.Minute
+)
 
 // Controller is the controller implementation for cronhpa resources
 type Controller struct {
@@ -56,17 +78,76 @@ type Controller struct {
 
 	restMapper      *restmapper.DeferredDiscoveryRESTMapper
 	scaleNamespacer scaleclient.ScalesGetter
+	// dynamicClient reads pod templates of scale targets.
+	dynamicClient dynamic.Interface
 
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
 	recorder record.EventRecorder
+
+	// cronhpaListers list cronhpas of each watched namespace, or of all namespaces.
+	cronhpaListers       []cronhpalisters.CronHPALister
+	cronhpaListersSynced []cache.InformerSynced
+	// freezeLister lists ScalingFreezes, which hold back scheduled actions.
+	// It is nil if freezes are not watched.
+	freezeLister       cronhpalisters.ScalingFreezeLister
+	freezeListerSynced cache.InformerSynced
+	// conflicts finds CronHPAs and HPAs scaling the targets of cronhpas.
+	conflicts *conflict.Index
+
+	// controllerName is the name of this controller. It only acts on cronhpas
+	// assigned to it by spec.controllerName.
+	controllerName string
+
+	// sharder decides which cronhpas this replica acts on. It is nil if all
+	// cronhpas are synced by this replica.
+	sharder *sharding.Sharder
+
+	// syncedCronHPAs are the keys of cronhpas in the last sync, which are used
+	// to drop metrics and scheduled actions of deleted cronhpas.
+	syncedCronHPAs sets.String
+	// limiter limits scale operations.
+	limiter *scaleLimiter
+
+	// schedule holds the upcoming actions of synced cronhpas.
+	schedule *scheduleTable
+
+	// policyNamespace is the namespace of the default CronHPAPolicies.
+	policyNamespace string
+
+	// pause is the switch pausing all scaling.
+	pause *pause.Switch
+	// paused is whether scaling was paused on the last sync.
+	paused bool
+
+	// notifier sends notifications of scale events. It may be nil.
+	notifier notify.Notifier
+	// notified holds the scheduled time of the latest events notified once,
+	// keyed by notifiedKey, so that retries don't notify again.
+	notified sync.Map
+	// httpHooks holds the HTTP hook calls in flight by httpHookKey, which
+	// are checked on later syncs.
+	httpHooks sync.Map
+
+	lastSyncLock sync.RWMutex
+	// lastSyncTime is when the latest sync of all cronhpas finished.
+	lastSyncTime time.Time
 }
 
 // NewController returns a new cronhpa controller
 func NewController(
 	kubeclientset kubernetes.Interface,
 	cronhpaclientset clientset.Interface,
-	rootClientBuilder controllerpkg.ControllerClientBuilder) (*Controller, error) {
+	cronhpaInformers []cronhpainformers.CronHPAInformer,
+	freezeInformer cronhpainformers.ScalingFreezeInformer,
+	conflicts *conflict.Index,
+	rootClientBuilder controllerpkg.ControllerClientBuilder,
+	controllerName string,
+	sharder *sharding.Sharder,
+	scaleRateLimit ScaleRateLimit,
+	pauseSwitch *pause.Switch,
+	policyNamespace string,
+	notifier notify.Notifier) (*Controller, error) {
 
 	// Create event broadcaster
 	// Add cronhpa-controller types to the default Kubernetes Scheme so Events can be
@@ -93,28 +174,65 @@ func NewController(
 	if err != nil {
 		return nil, err
 	}
+	dynamicClient, err := dynamic.NewForConfig(cronhpaClientConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	controller := &Controller{
 		kubeclientset:    kubeclientset,
 		cronhpaclientset: cronhpaclientset,
 		restMapper:       restMapper,
 		scaleNamespacer:  scaleClient,
+		dynamicClient:    dynamicClient,
 		recorder:         recorder,
+		controllerName:   controllerName,
+		sharder:          sharder,
+		limiter:          newScaleLimiter(scaleRateLimit),
+		syncedCronHPAs:   sets.NewString(),
+		schedule:         newScheduleTable(),
+		conflicts:        conflicts,
+		pause:            pauseSwitch,
+		policyNamespace:  policyNamespace,
+		notifier:         notifier,
+	}
+	controller.freezeListerSynced = func() bool { return true }
+	if freezeInformer != nil {
+		controller.freezeLister = freezeInformer.Lister()
+		controller.freezeListerSynced = freezeInformer.Informer().HasSynced
+	}
+	for _, informer := range cronhpaInformers {
+		controller.cronhpaListers = append(controller.cronhpaListers, informer.Lister())
+		controller.cronhpaListersSynced = append(controller.cronhpaListersSynced, informer.Informer().HasSynced)
 	}
 
 	return controller, nil
 }
 
-func (c *Controller) Run(stopCh <-chan struct{}) error {
+// Run syncs cronhpas with workers goroutines until ctx is done. It returns
+// after in-flight syncs finish.
+func (c *Controller) Run(ctx context.Context, workers int) error {
 	defer runtime.HandleCrash()
 
 	// Start the informer factories to begin populating the informer caches
 	klog.Info("Starting cronhpa controller")
 
-	go wait.Until(c.syncAll, 10*time.Second, stopCh)
-	go wait.Until(func() { c.restMapper.Reset() }, 30*time.Second, stopCh)
-	<-stopCh
-	klog.Info("Shutting down")
+	synced := append([]cache.InformerSynced{c.freezeListerSynced, c.conflicts.HasSynced, c.pause.HasSynced}, c.cronhpaListersSynced...)
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return fmt.Errorf("failed to wait for caches to sync")
+	}
+	c.setLastSyncTime(time.Now())
+
+	var syncs wait.Group
+	syncs.Start(func() {
+		wait.Until(func() { c.syncAll(ctx, workers) }, syncPeriod, ctx.Done())
+	})
+	go wait.Until(func() { c.restMapper.Reset() }, 30*time.Second, ctx.Done())
+	<-ctx.Done()
+	klog.Info("Shutting down, waiting for in-flight syncs")
+	syncs.Wait()
+	c.setLastSyncTime(time.Time{})
+	klog.Info("Shut down")
 
 	return nil
 }
@@ -123,56 +241,501 @@ func (c *Controller) GetEventRecorder() record.EventRecorder {
 	return c.recorder
 }
 
-func (c *Controller) syncAll() {
+// HasSynced returns true if all cronhpa, freeze, conflict and pause informers have synced.
+func (c *Controller) HasSynced() bool {
+	if !c.freezeListerSynced() || !c.conflicts.HasSynced() || !c.pause.HasSynced() {
+		return false
+	}
+	for _, synced := range c.cronhpaListersSynced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckSync returns an error if the controller is running but the sync loop
+// has made no progress recently.
+func (c *Controller) CheckSync() error {
+	c.lastSyncLock.RLock()
+	defer c.lastSyncLock.RUnlock()
+	if c.lastSyncTime.IsZero() {
+		// Not running, e.g. not leading
+		return nil
+	}
+	if since := time.Since(c.lastSyncTime); since > syncStalledTimeout {
+		return fmt.Errorf("no sync finished in the last %v", since)
+	}
+	return nil
+}
+
+func (c *Controller) setLastSyncTime(t time.Time) {
+	c.lastSyncLock.Lock()
+	defer c.lastSyncLock.Unlock()
+	c.lastSyncTime = t
+}
+
+// UpcomingActions returns the upcoming actions of all synced cronhpas sorted by time.
+func (c *Controller) UpcomingActions() []ScheduledAction {
+	return c.schedule.list()
+}
+
+func (c *Controller) syncAll(ctx context.Context, workers int) {
 	klog.V(4).Infof("Starting sync all")
-	cronhpas, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(metav1.NamespaceAll).List(metav1.ListOptions{})
-	if err != nil {
-		klog.Errorf("Failed to list cronhpas")
+	start := time.Now()
+	defer func() {
+		metrics.SyncDuration.Observe(time.Since(start).Seconds())
+	}()
+
+	var cronhpas []*v1.CronHPA
+	for _, lister := range c.cronhpaListers {
+		list, err := lister.List(labels.Everything())
+		if err != nil {
+			klog.Errorf("Failed to list cronhpas")
+			return
+		}
+		for _, cronhpa := range list {
+			// Leave cronhpas assigned to other controllers alone
+			if v1.GetControllerName(cronhpa) == c.controllerName {
+				cronhpas = append(cronhpas, cronhpa)
+			}
+		}
+	}
+	if c.sharder != nil {
+		keys := make([]string, 0, len(cronhpas))
+		for _, cronhpa := range cronhpas {
+			keys = append(keys, getCronHPAFullName(cronhpa))
+		}
+		owned := c.sharder.Owned(keys)
+		ownedCronHPAs := make([]*v1.CronHPA, 0, owned.Len())
+		for _, cronhpa := range cronhpas {
+			if owned.Has(getCronHPAFullName(cronhpa)) {
+				ownedCronHPAs = append(ownedCronHPAs, cronhpa)
+			}
+		}
+		cronhpas = ownedCronHPAs
+	}
+	metrics.ManagedCronHPAs.Set(float64(len(cronhpas)))
+
+	paused, reason := c.pause.Paused()
+	if paused != c.paused {
+		if paused {
+			klog.Warningf("Scaling is paused: %s", reason)
+		} else {
+			klog.Infof("Scaling has resumed")
+		}
+		c.paused = paused
+	}
+	if paused {
+		metrics.Paused.Set(1)
+	} else {
+		metrics.Paused.Set(0)
+	}
+
+	// Plan due actions of all cronhpas first, and then execute them in order,
+	// so that scale-ups are not delayed by scale-downs. Both are done with
+	// workers, so that a slow call doesn't delay other cronhpas.
+	now := time.Now()
+	planned := make([]*scaleAction, len(cronhpas))
+	parallelize(ctx, workers, len(cronhpas), func(i int) {
+		klog.V(4).Infof("Sync cronhpa: %s", getCronHPAFullName(cronhpas[i]))
+		cronhpa := cronhpas[i].DeepCopy()
+		if c.syncConflict(cronhpa) || c.syncPause(cronhpa, paused, reason) {
+			c.updateSchedule(cronhpa, now)
+			return
+		}
+		planned[i] = c.plan(ctx, cronhpa, now)
+	})
+	var actions []*scaleAction
+	for _, action := range planned {
+		if action != nil {
+			actions = append(actions, action)
+		}
+	}
+	sort.SliceStable(actions, func(i, j int) bool {
+		if actions[i].isScaleUp() != actions[j].isScaleUp() {
+			return actions[i].isScaleUp()
+		}
+		return actions[i].scheduledTime.Before(actions[j].scheduledTime)
+	})
+	parallelize(ctx, workers, len(actions), func(i int) {
+		c.execute(ctx, actions[i])
+	})
+	if ctx.Err() != nil {
+		klog.V(4).Infof("Stop syncing: %v", ctx.Err())
 		return
 	}
-	for _, cronhpa := range cronhpas.Items {
-		klog.V(4).Infof("Sync cronhpa: %s", cronhpa.Name)
-		c.syncOne(&cronhpa)
+
+	synced := sets.NewString()
+	for _, cronhpa := range cronhpas {
+		synced.Insert(getCronHPAFullName(cronhpa))
+	}
+	// Drop metrics and scheduled actions of deleted cronhpas
+	for _, key := range c.syncedCronHPAs.Difference(synced).UnsortedList() {
+		namespace, name, _ := cache.SplitMetaNamespaceKey(key)
+		metrics.ForgetCronHPA(namespace, name)
+		c.schedule.delete(key)
+		c.forgetNotified(key)
+		c.forgetHTTPHooks(key)
 	}
+	c.syncedCronHPAs = synced
+	c.setLastSyncTime(time.Now())
 }
 
-func (c *Controller) syncOne(cronhpa *v1.CronHPA) {
-	now := time.Now()
+// parallelize calls fn with 0 to n-1 in order with workers goroutines, until ctx is done.
+func parallelize(ctx context.Context, workers, n int, fn func(i int)) {
+	queue := make(chan int)
+	var group wait.Group
+	for w := 0; w < workers; w++ {
+		group.Start(func() {
+			for i := range queue {
+				fn(i)
+			}
+		})
+	}
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		queue <- i
+	}
+	close(queue)
+	group.Wait()
+}
+
+// scaleAction is a due schedule of a cronhpa, whose target scale has been read.
+type scaleAction struct {
+	cronhpa *v1.CronHPA
+	cron    v1.Cron
+	// manual is the kind of a manual action, or empty for a schedule.
+	manual manualKind
+	// scheduledTime is when the schedule should have fired, without jitter,
+	// or when a manual action was accepted.
+	scheduledTime time.Time
+	// plannedTime is when the action was planned.
+	plannedTime time.Time
+	scale       *autoscalingv1.Scale
+	targetGR    schema.GroupResource
+}
+
+func (a *scaleAction) isScaleUp() bool {
+	return a.cron.TargetReplicas > a.scale.Spec.Replicas
+}
+
+// plan returns the due action of cronhpa with its target scale, or nil if no
+// schedule is due, it's held back by a ScalingFreeze, refused by a
+// CronHPAPolicy, or the scale can't be read. Manual actions requested by annotations come before schedules.
+func (c *Controller) plan(ctx context.Context, cronhpa *v1.CronHPA, now time.Time) *scaleAction {
+	if c.acceptAnnotations(cronhpa, now) {
+		// Accepted actions are planned on the next sync
+		c.updateSchedule(cronhpa, now)
+		return nil
+	}
+	action := c.planManual(cronhpa, now)
+	if action == nil {
+		action = c.planSchedule(ctx, cronhpa, now)
+	}
+	if action == nil {
+		action = planOverrideExpiry(cronhpa, now)
+	}
+	if action == nil {
+		c.updateSchedule(cronhpa, now)
+		return nil
+	}
+
+	cron := action.cron
+	if c.sharder != nil && !c.isLatest(cronhpa) {
+		// Another replica may have fired it during a shard handover.
+		klog.V(4).Infof("Skip firing %s on a stale cache", getCronHPAFullName(cronhpa))
+		c.updateSchedule(cronhpa, now)
+		return nil
+	}
+	if action.manual == "" || action.manual == manualRestore {
+		// Manual triggers and overrides are requested explicitly, so they
+		// are not held back by freezes
+		if freeze := c.activeFreeze(cronhpa, now); freeze != nil {
+			c.holdBack(action, freeze, now)
+			c.updateSchedule(cronhpa, now)
+			return nil
+		}
+	}
+	// Restores return to replicas which have been in effect before
+	if action.manual != manualRestore && !c.enforcePolicies(action, now) {
+		c.updateSchedule(cronhpa, now)
+		return nil
+	}
+	klog.V(4).Infof("Scale %s to replicas %d for cron %s", getCronHPAFullName(cronhpa),
+		cron.TargetReplicas, v1.GetCronName(&cron))
+	scale, targetGR, err := c.getScale(ctx, cronhpa)
+	if err != nil && ctx.Err() != nil {
+		// Stopped meanwhile, which is not a failure of the action
+		klog.V(4).Infof("Skip scaling %s: %v", getCronHPAFullName(cronhpa), ctx.Err())
+		return nil
+	}
+	if err != nil {
+		klog.Errorf("Failed to scale %s to replicas %d: %v", getCronHPAFullName(cronhpa), cron.TargetReplicas, err)
+		metrics.ScaleAttempts.WithLabelValues(cronhpa.Namespace, cronhpa.Name).Inc()
+		metrics.ScaleFailures.WithLabelValues(cronhpa.Namespace, cronhpa.Name).Inc()
+		c.recordExecution(cronhpa, cron, action.scheduledTime, now, 0, 0, err)
+		c.cleanupExecutions(cronhpa)
+		c.notifyOnce(notify.ScaleFailed, cronhpa, cron, action.scheduledTime, 0, cron.TargetReplicas, err.Error())
+		c.updateSchedule(cronhpa, now)
+		return nil
+	}
+	c.notifyOnce(notify.ScheduleFired, cronhpa, cron, action.scheduledTime, scale.Spec.Replicas, cron.TargetReplicas,
+		fmt.Sprintf("Schedule fired to scale from %d to %d replicas", scale.Spec.Replicas, cron.TargetReplicas))
+	action.plannedTime = now
+	action.scale = scale
+	action.targetGR = targetGR
+	return action
+}
+
+// planSchedule returns the action of the latest due cron of cronhpa without
+// its target scale, or nil if none is due. It notices upcoming crons, skips
+// due crons annotated by SkipNextAnnotation, and handles crons missed while
+// paused first.
+func (c *Controller) planSchedule(ctx context.Context, cronhpa *v1.CronHPA, now time.Time) *scaleAction {
+	if isOverrideActive(cronhpa, now) {
+		klog.V(4).Infof("Schedules of %s are suppressed by an override", getCronHPAFullName(cronhpa))
+		return nil
+	}
 	latestSchedledTime := getLatestScheduledTime(cronhpa)
+	jitter := getJitter(cronhpa)
+	if action, missed := c.planMissed(cronhpa, latestSchedledTime, jitter, now); missed {
+		return action
+	}
 	for _, cron := range cronhpa.Spec.Crons {
 		sched, err := cronutil.ParseStandard(cron.Schedule)
 		if err != nil {
 			klog.Errorf("Unparseable schedule: %s : %s", cron.Schedule, err)
+			continue
 		}
+		replicas, ok := v1.GetCronReplicas(cronhpa, &cron)
+		if !ok {
+			klog.Errorf("Unknown profile %s of cron %s of cronhpa %s", cron.Profile, v1.GetCronName(&cron), getCronHPAFullName(cronhpa))
+			continue
+		}
+		cron.TargetReplicas = replicas
 		t := sched.Next(latestSchedledTime)
 		klog.V(4).Infof("Next schedule for %s of cronhpa %s: %v", cron.Schedule, getCronHPAFullName(cronhpa), t)
-		if !t.After(now) {
-			klog.V(4).Infof("Scale %s to replicas %d for schedule %s", getCronHPAFullName(cronhpa),
-				cron.TargetReplicas, cron.Schedule)
-			// Set new replicas
-			if err := c.scale(cronhpa, cron.TargetReplicas); err != nil {
-				klog.Errorf("Failed to scale %s to replicas %d: %v", getCronHPAFullName(cronhpa), cron.TargetReplicas, err)
-				return
-			}
-			// Update status
-			cronhpa.Status.LastScheduleTime = &metav1.Time{Time: time.Now()}
-			if _, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Update(cronhpa); err != nil {
-				klog.Errorf("Failed to update cronhpa %s's LastScheduleTime(%+v): %v",
-					getCronHPAFullName(cronhpa), cronhpa.Status.LastScheduleTime.Time, err)
-			}
+		if t.Add(jitter).After(now) {
+			c.noticeUpcoming(ctx, cronhpa, cron, t, t.Add(jitter), now)
+			continue
+		}
+		// Several schedules are due if they have been held back, e.g. by a
+		// deferring ScalingFreeze, and only the latest of them is run, so a
+		// stale one doesn't override a later one
+		if latest, latestTime, ok := getLatestMissed(cronhpa, latestSchedledTime, jitter, now); ok {
+			cron, t = latest, latestTime
+		}
+		if c.skipNext(cronhpa, cron, t, now) {
+			return nil
+		}
+		return &scaleAction{cronhpa: cronhpa, cron: cron, scheduledTime: t}
+	}
+	return nil
+}
 
+// execute scales the target of action once allowed by the rate limiter, and
+// records the result.
+func (c *Controller) execute(ctx context.Context, action *scaleAction) {
+	cronhpa, cron := action.cronhpa, action.cron
+	defer func() {
+		c.updateSchedule(cronhpa, time.Now())
+	}()
+
+	if err := c.limiter.wait(ctx, cronhpa.Namespace); err != nil {
+		klog.V(4).Infof("Skip scaling %s: %v", getCronHPAFullName(cronhpa), err)
+		return
+	}
+	if paused, _ := c.pause.Paused(); paused {
+		// Paused since planned, keep the action due
+		klog.V(4).Infof("Skip scaling %s: paused", getCronHPAFullName(cronhpa))
+		return
+	}
+	now := time.Now()
+	metrics.ScaleQueueDelay.Observe(now.Sub(action.plannedTime).Seconds())
+
+	oldStatus := cronhpa.Status.DeepCopy()
+	var oldReplicas, replicas int32
+	var complete bool
+	var err error
+	if hook := cronhpa.Status.PostScaleHook; cronhpa.Spec.PostScale != nil && hook != nil &&
+		hook.ScheduledTime.Time.Equal(action.scheduledTime) {
+		// The target has been scaled, only the PostScale hook is left
+		oldReplicas, replicas, complete = hook.CurrentReplicas, hook.TargetReplicas, true
+	} else {
+		oldReplicas, replicas, complete, err = c.scale(ctx, action)
+	}
+	if err != nil && ctx.Err() != nil {
+		// Stopped meanwhile, keep the action due
+		klog.V(4).Infof("Skip scaling %s: %v", getCronHPAFullName(cronhpa), ctx.Err())
+		return
+	}
+	if err == nil && complete && cronhpa.Spec.PostScale != nil {
+		complete, err = c.runHook(ctx, cronhpa, action, v1.PostScale, cronhpa.Spec.PostScale, oldReplicas, replicas)
+	}
+	if err != nil || complete {
+		// An action spanning several syncs, e.g. a drain or a hook, is counted
+		// once it fails or completes, rather than on every sync
+		metrics.ScaleAttempts.WithLabelValues(cronhpa.Namespace, cronhpa.Name).Inc()
+	}
+	if err != nil {
+		klog.Errorf("Failed to scale %s to replicas %d: %v", getCronHPAFullName(cronhpa), cron.TargetReplicas, err)
+		metrics.ScaleFailures.WithLabelValues(cronhpa.Namespace, cronhpa.Name).Inc()
+		if !isFinal(err) {
+			// Retry on the next sync
+			c.recordExecution(cronhpa, cron, action.scheduledTime, now, oldReplicas, oldReplicas, err)
+			c.cleanupExecutions(cronhpa)
+			c.notifyOnce(notify.ScaleFailed, cronhpa, cron, action.scheduledTime, oldReplicas, cron.TargetReplicas, err.Error())
+			if !apiequality.Semantic.DeepEqual(oldStatus, &cronhpa.Status) {
+				c.updateStatus(cronhpa)
+			}
 			return
 		}
 	}
+	if !complete {
+		// Keep the schedule due, so that the next sync continues the action
+		if !apiequality.Semantic.DeepEqual(oldStatus, &cronhpa.Status) {
+			c.updateStatus(cronhpa)
+		}
+		return
+	}
+	if err == nil && action.manual == "" {
+		metrics.ScheduleLag.WithLabelValues(cronhpa.Namespace, cronhpa.Name).Observe(now.Sub(action.scheduledTime.Add(getJitter(cronhpa))).Seconds())
+	}
+	c.recordExecution(cronhpa, cron, action.scheduledTime, now, oldReplicas, replicas, err)
+	c.cleanupExecutions(cronhpa)
+	if err == nil {
+		c.notify(notify.ScaleSucceeded, cronhpa, cron, action.scheduledTime, oldReplicas, replicas,
+			fmt.Sprintf("Scaled from %d to %d replicas", oldReplicas, replicas))
+	} else if isSkipped(err) {
+		c.notify(notify.ScaleSkipped, cronhpa, cron, action.scheduledTime, oldReplicas, cron.TargetReplicas, err.Error())
+	} else {
+		c.notify(notify.ScaleFailed, cronhpa, cron, action.scheduledTime, oldReplicas, replicas, err.Error())
+	}
+	// Update status
+	if action.manual != "" {
+		c.finishManual(action, oldReplicas, replicas, err)
+		c.updateStatus(cronhpa)
+		return
+	}
+	cronhpa.Status.LastScheduleTime = &metav1.Time{Time: time.Now()}
+	if err == nil {
+		cronhpa.Status.ActiveProfile = newProfileStatus(&cron, v1.ProfileSourceCron)
+	}
+	if isOverrideExpired(cronhpa, now) {
+		expireOverride(cronhpa, fmt.Sprintf("Reverted to cron %s", v1.GetCronName(&cron)))
+	}
+	if _, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Update(cronhpa); err != nil {
+		klog.Errorf("Failed to update cronhpa %s's LastScheduleTime(%+v): %v",
+			getCronHPAFullName(cronhpa), cronhpa.Status.LastScheduleTime.Time, err)
+	}
+}
+
+// isFinal returns true if err ends an action, which is recorded once rather
+// than retried on the next sync.
+func isFinal(err error) bool {
+	switch err.(type) {
+	case *hookAbortedError, *quotaRefusedError:
+		return true
+	}
+	return false
+}
+
+// isSkipped returns true if err means that the target has been left as it is
+// on purpose, by an aborted PreScale hook or a refused quota.
+func isSkipped(err error) bool {
+	if aborted, ok := err.(*hookAbortedError); ok {
+		return aborted.phase == v1.PreScale
+	}
+	_, refused := err.(*quotaRefusedError)
+	return refused
+}
+
+// scale reads the scale of the target of action again, runs the PreScale hook
+// and scales the target, adjusted by the policies of its cronhpa. It returns
+// the replicas before and after, and false if the action needs more syncs to
+// reach them. If the PreScale hook aborts or the quota refuses the scale-up,
+// the replicas are unchanged and the error is a *hookAbortedError or a
+// *quotaRefusedError.
+func (c *Controller) scale(ctx context.Context, action *scaleAction) (int32, int32, bool, error) {
+	cronhpa := action.cronhpa
+	// The scale read on planning orders actions, but may be stale after
+	// waiting for the limiter
+	scale, targetGR, err := c.getScale(ctx, cronhpa)
+	if err != nil {
+		return action.scale.Spec.Replicas, action.scale.Spec.Replicas, true, err
+	}
+	action.scale, action.targetGR = scale, targetGR
+	oldReplicas := action.scale.Spec.Replicas
+	replicas := action.cron.TargetReplicas
+	if cronhpa.Spec.PreScale != nil {
+		done, err := c.runHook(ctx, cronhpa, action, v1.PreScale, cronhpa.Spec.PreScale, oldReplicas, replicas)
+		if err != nil || !done {
+			return oldReplicas, oldReplicas, done, err
+		}
+	}
+
+	// complete is false if the action needs more syncs to reach its replicas
+	complete := true
+	if replicas > oldReplicas && (cronhpa.Spec.QuotaPolicy == v1.QuotaPolicyClamp || cronhpa.Spec.QuotaPolicy == v1.QuotaPolicyRefuse) {
+		replicas, err = c.applyQuotaPolicy(cronhpa, action.scale, replicas)
+	}
+	if replicas < oldReplicas && cronhpa.Spec.RespectPDB {
+		replicas, complete, err = c.applyPDB(cronhpa, action.scale, replicas)
+	}
+	if err == nil && replicas < oldReplicas && cronhpa.Spec.PodDeletionCost != nil {
+		// Deletion costs are hints, so scale down regardless of failures
+		if err := c.setDeletionCosts(ctx, cronhpa, action.scale); err != nil {
+			c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedSetDeletionCost", err.Error())
+		}
+	}
+	// drained is false until the surplus pods are safe to remove
+	drained := true
+	if replicas < oldReplicas && cronhpa.Spec.Drain != nil {
+		if err == nil {
+			drained, err = c.drain(cronhpa, action, replicas)
+		}
+	} else if cronhpa.Status.Drain != nil {
+		c.cancelDrain(cronhpa)
+	}
+	// Set new replicas
+	if err == nil && drained {
+		err = c.updateScale(cronhpa, action.scale, action.targetGR, replicas)
+	}
+	if err != nil {
+		return oldReplicas, oldReplicas, true, err
+	}
+	return oldReplicas, replicas, complete && drained, nil
+}
+
+// updateStatus updates cronhpa with its status. It returns false if failed.
+func (c *Controller) updateStatus(cronhpa *v1.CronHPA) bool {
+	if _, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Update(cronhpa); err != nil {
+		klog.Errorf("Failed to update cronhpa %s's status: %v", getCronHPAFullName(cronhpa), err)
+		return false
+	}
+	return true
+}
+
+// isLatest returns true if cronhpa is the latest version on the apiserver.
+func (c *Controller) isLatest(cronhpa *v1.CronHPA) bool {
+	latest, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Get(cronhpa.Name, metav1.GetOptions{})
+	if err != nil {
+		klog.Errorf("Failed to get cronhpa %s: %v", getCronHPAFullName(cronhpa), err)
+		return false
+	}
+	return latest.ResourceVersion == cronhpa.ResourceVersion
 }
 
-func (c *Controller) scale(cronhpa *v1.CronHPA, replicas int32) error {
-	reference := fmt.Sprintf("%s/%s/%s", cronhpa.Spec.ScaleTargetRef.Kind, cronhpa.Namespace, cronhpa.Spec.ScaleTargetRef.Name)
+// getScale returns the scale of cronhpa's target, and the group-resource to update it.
+func (c *Controller) getScale(ctx context.Context, cronhpa *v1.CronHPA) (*autoscalingv1.Scale, schema.GroupResource, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, schema.GroupResource{}, err
+	}
 
 	targetGV, err := schema.ParseGroupVersion(cronhpa.Spec.ScaleTargetRef.APIVersion)
 	if err != nil {
 		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedGetScale", err.Error())
-		return fmt.Errorf("invalid API version in scale target reference: %v", err)
+		return nil, schema.GroupResource{}, fmt.Errorf("invalid API version in scale target reference: %v", err)
 	}
 
 	targetGK := schema.GroupKind{
@@ -183,33 +746,40 @@ func (c *Controller) scale(cronhpa *v1.CronHPA, replicas int32) error {
 	mappings, err := c.restMapper.RESTMappings(targetGK)
 	if err != nil {
 		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedGetScale", err.Error())
-		return fmt.Errorf("unable to determine resource for scale target reference: %v", err)
+		return nil, schema.GroupResource{}, fmt.Errorf("unable to determine resource for scale target reference: %v", err)
 	}
 
 	scale, targetGR, err := c.scaleForResourceMappings(cronhpa.Namespace, cronhpa.Spec.ScaleTargetRef.Name, mappings)
 	if err != nil {
 		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedGetScale", err.Error())
-		return fmt.Errorf("failed to query scale subresource for %s: %v", reference, err)
+		return nil, schema.GroupResource{}, fmt.Errorf("failed to query scale subresource for %s: %v", getScaleReference(cronhpa), err)
 	}
+	return scale, targetGR, nil
+}
 
-	if scale.Spec.Replicas != replicas {
-		oldReplicas := scale.Spec.Replicas
-		scale.Spec.Replicas = replicas
-		_, err = c.scaleNamespacer.Scales(cronhpa.Namespace).Update(targetGR, scale)
-		if err != nil {
-			c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedRescale", err.Error())
-			return fmt.Errorf("failed to rescale %s: %v", reference, err)
-		}
-		c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "SuccessfulRescale", "New size: %d", replicas)
-		klog.Infof("Successful scale of %s, old size: %d, new size: %d",
-			getCronHPAFullName(cronhpa), oldReplicas, replicas)
-	} else {
+// updateScale sets the replicas of scale of cronhpa's target if they differ.
+func (c *Controller) updateScale(cronhpa *v1.CronHPA, scale *autoscalingv1.Scale, targetGR schema.GroupResource, replicas int32) error {
+	oldReplicas := scale.Spec.Replicas
+	if oldReplicas == replicas {
 		klog.V(4).Infof("No need to scale %s to %v, same replicas", getCronHPAFullName(cronhpa), replicas)
+		return nil
 	}
-
+	scale = scale.DeepCopy()
+	scale.Spec.Replicas = replicas
+	if _, err := c.scaleNamespacer.Scales(cronhpa.Namespace).Update(targetGR, scale); err != nil {
+		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedRescale", err.Error())
+		return fmt.Errorf("failed to rescale %s: %v", getScaleReference(cronhpa), err)
+	}
+	c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "SuccessfulRescale", "New size: %d", replicas)
+	klog.Infof("Successful scale of %s, old size: %d, new size: %d",
+		getCronHPAFullName(cronhpa), oldReplicas, replicas)
 	return nil
 }
 
+func getScaleReference(cronhpa *v1.CronHPA) string {
+	return fmt.Sprintf("%s/%s/%s", cronhpa.Spec.ScaleTargetRef.Kind, cronhpa.Namespace, cronhpa.Spec.ScaleTargetRef.Name)
+}
+
 // scaleForResourceMappings attempts to fetch the scale for the
 // resource with the given name and namespace, trying each RESTMapping
 // in turn until a working one is found.  If none work, the first error
@@ -247,6 +817,26 @@ func getLatestScheduledTime(cronhpa *v1.CronHPA) time.Time {
 	}
 }
 
+// updateSchedule records the upcoming actions of cronhpa, and sets the seconds
+// until its next schedule fires. The latter is negative if a schedule is overdue,
+// e.g. its scaling keeps failing.
+func (c *Controller) updateSchedule(cronhpa *v1.CronHPA, now time.Time) {
+	actions := getScheduledActions(cronhpa)
+	c.schedule.set(getCronHPAFullName(cronhpa), actions)
+
+	if len(actions) == 0 {
+		metrics.NextFire.DeleteLabelValues(cronhpa.Namespace, cronhpa.Name)
+		return
+	}
+	next := actions[0].Time
+	for _, action := range actions[1:] {
+		if action.Time.Before(next) {
+			next = action.Time
+		}
+	}
+	metrics.NextFire.WithLabelValues(cronhpa.Namespace, cronhpa.Name).Set(next.Sub(now).Seconds())
+}
+
 func getCronHPAFullName(cronhpa *v1.CronHPA) string {
 	return cronhpa.Namespace + "/" + cronhpa.Name
 }
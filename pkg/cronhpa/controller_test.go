@@ -17,4 +17,439 @@
 
 package cronhpa
 
-// TODO: add test cases
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/client/clientset/versioned/fake"
+	cronhpascheme "tkestack.io/cron-hpa/pkg/client/clientset/versioned/scheme"
+	informers "tkestack.io/cron-hpa/pkg/client/informers/externalversions"
+	cronhpainformers "tkestack.io/cron-hpa/pkg/client/informers/externalversions/cronhpacontroller/v1"
+	cronhpalisters "tkestack.io/cron-hpa/pkg/client/listers/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/conflict"
+	"tkestack.io/cron-hpa/pkg/metrics"
+	"tkestack.io/cron-hpa/pkg/pause"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	cacheddiscovery "k8s.io/client-go/discovery/cached"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	kubescheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/restmapper"
+	scalefake "k8s.io/client-go/scale/fake"
+	core "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+var (
+	deploymentsResource = appsv1.SchemeGroupVersion.WithResource("deployments")
+	podsResource        = corev1.SchemeGroupVersion.WithResource("pods")
+)
+
+// testController is a controller on fake clients, which scales apps/v1
+// Deployments with the scale subresource.
+type testController struct {
+	*Controller
+	kubeTracker    core.ObjectTracker
+	cronhpaClient  *fake.Clientset
+	cronhpaIndexer interface{ Update(interface{}) error }
+	recorder       *record.FakeRecorder
+}
+
+// newTestController returns a testController with objects, where Deployments
+// among them can be scaled.
+func newTestController(t *testing.T, objects ...runtime.Object) *testController {
+	kubeTracker := core.NewObjectTracker(kubescheme.Scheme, kubescheme.Codecs.UniversalDecoder())
+	var deployments []runtime.Object
+	for _, object := range objects {
+		if err := kubeTracker.Add(object); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := object.(*appsv1.Deployment); ok {
+			deployments = append(deployments, object)
+		}
+	}
+	kubeClient := &kubefake.Clientset{}
+	// The object tracker doesn't support merge patches
+	kubeClient.AddReactor("patch", "pods", func(action core.Action) (bool, runtime.Object, error) {
+		patch := action.(core.PatchAction)
+		if patch.GetPatchType() != types.MergePatchType {
+			return false, nil, nil
+		}
+		obj, err := kubeTracker.Get(podsResource, patch.GetNamespace(), patch.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		original, err := json.Marshal(obj)
+		if err != nil {
+			return true, nil, err
+		}
+		patched, err := jsonpatch.MergePatch(original, patch.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		pod := &corev1.Pod{}
+		if err := json.Unmarshal(patched, pod); err != nil {
+			return true, nil, err
+		}
+		return true, pod, kubeTracker.Update(podsResource, pod, pod.Namespace)
+	})
+	kubeClient.AddReactor("*", "*", core.ObjectReaction(kubeTracker))
+
+	scaleClient := &scalefake.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action core.Action) (bool, runtime.Object, error) {
+		get := action.(core.GetAction)
+		obj, err := kubeTracker.Get(deploymentsResource, get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		deployment := obj.(*appsv1.Deployment)
+		selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+		if err != nil {
+			return true, nil, err
+		}
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Namespace: deployment.Namespace, Name: deployment.Name},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: *deployment.Spec.Replicas},
+			Status:     autoscalingv1.ScaleStatus{Replicas: deployment.Status.Replicas, Selector: selector.String()},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action core.Action) (bool, runtime.Object, error) {
+		scale := action.(core.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		obj, err := kubeTracker.Get(deploymentsResource, scale.Namespace, scale.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		deployment := obj.(*appsv1.Deployment).DeepCopy()
+		deployment.Spec.Replicas = &scale.Spec.Replicas
+		return true, scale, kubeTracker.Update(deploymentsResource, deployment, deployment.Namespace)
+	})
+
+	discovery := kubefake.NewSimpleClientset()
+	discovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "apps/v1",
+		APIResources: []metav1.APIResource{{Name: "deployments", Namespaced: true, Kind: "Deployment"}},
+	}}
+	dynamicScheme := runtime.NewScheme()
+	appsv1.AddToScheme(dynamicScheme)
+
+	cronhpaClient := newFakeClientset()
+	informer := informers.NewSharedInformerFactory(cronhpaClient, 0).Cronhpacontroller().V1().CronHPAs()
+	conflicts, err := conflict.NewIndex([]cronhpainformers.CronHPAInformer{informer}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pauseSwitch, _ := pause.New(kubeClient, "", "", "")
+	recorder := record.NewFakeRecorder(100)
+	return &testController{
+		Controller: &Controller{
+			kubeclientset:        kubeClient,
+			cronhpaclientset:     cronhpaClient,
+			restMapper:           restmapper.NewDeferredDiscoveryRESTMapper(cacheddiscovery.NewMemCacheClient(discovery.Discovery())),
+			scaleNamespacer:      scaleClient,
+			dynamicClient:        dynamicfake.NewSimpleDynamicClient(dynamicScheme, deployments...),
+			recorder:             recorder,
+			cronhpaListers:       []cronhpalisters.CronHPALister{informer.Lister()},
+			cronhpaListersSynced: []cache.InformerSynced{informer.Informer().HasSynced},
+			freezeListerSynced:   func() bool { return true },
+			conflicts:            conflicts,
+			controllerName:       v1.DefaultControllerName,
+			syncedCronHPAs:       sets.NewString(),
+			limiter:              newScaleLimiter(ScaleRateLimit{}),
+			schedule:             newScheduleTable(),
+			pause:                pauseSwitch,
+		},
+		kubeTracker:    kubeTracker,
+		cronhpaClient:  cronhpaClient,
+		cronhpaIndexer: informer.Informer().GetIndexer(),
+		recorder:       recorder,
+	}
+}
+
+// addCronHPA creates cronhpa, and adds it to the informer.
+func (tc *testController) addCronHPA(t *testing.T, cronhpa *v1.CronHPA) {
+	if _, err := tc.cronhpaClient.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Create(cronhpa); err != nil {
+		t.Fatal(err)
+	}
+	tc.cronhpaIndexer.Update(cronhpa)
+}
+
+// updateCronHPA updates cronhpa, and the informer with it.
+func (tc *testController) updateCronHPA(t *testing.T, cronhpa *v1.CronHPA) {
+	if _, err := tc.cronhpaClient.CronhpacontrollerV1().CronHPAs(cronhpa.Namespace).Update(cronhpa); err != nil {
+		t.Fatal(err)
+	}
+	tc.cronhpaIndexer.Update(cronhpa)
+}
+
+// getCronHPA returns the latest cronhpa named name, and updates the informer
+// with it, as a watch would.
+func (tc *testController) getCronHPA(t *testing.T, name string) *v1.CronHPA {
+	cronhpa, err := tc.cronhpaClient.CronhpacontrollerV1().CronHPAs("default").Get(name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.cronhpaIndexer.Update(cronhpa)
+	return cronhpa
+}
+
+// getReplicas returns the replicas of the Deployment named name.
+func (tc *testController) getReplicas(t *testing.T, name string) int32 {
+	obj, err := tc.kubeTracker.Get(deploymentsResource, "default", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *obj.(*appsv1.Deployment).Spec.Replicas
+}
+
+// getPod returns the pod named name.
+func (tc *testController) getPod(t *testing.T, name string) *corev1.Pod {
+	obj, err := tc.kubeTracker.Get(podsResource, "default", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return obj.(*corev1.Pod)
+}
+
+// events returns the reasons of the events recorded so far.
+func (tc *testController) events() []string {
+	var reasons []string
+	for {
+		select {
+		case event := <-tc.recorder.Events:
+			// Events are "<type> <reason> <message>"
+			var eventType, reason string
+			fmt.Sscanf(event, "%s %s", &eventType, &reason)
+			reasons = append(reasons, reason)
+		default:
+			return reasons
+		}
+	}
+}
+
+// newFakeClientset returns a fake clientset whose lists work, unlike those of
+// fake.NewSimpleClientset, which look lists up by a group not registered.
+// Names are generated for objects created with generateName.
+func newFakeClientset() *fake.Clientset {
+	tracker := core.NewObjectTracker(cronhpascheme.Scheme, cronhpascheme.Codecs.UniversalDecoder())
+	kinds := map[string]string{
+		"cronhpas":          "CronHPA",
+		"cronhpaexecutions": "CronHPAExecution",
+		"cronhpapolicies":   "CronHPAPolicy",
+	}
+	client := &fake.Clientset{}
+	generated := 0
+	client.AddReactor("create", "*", func(action core.Action) (bool, runtime.Object, error) {
+		object, err := meta.Accessor(action.(core.CreateAction).GetObject())
+		if err == nil && object.GetName() == "" && object.GetGenerateName() != "" {
+			generated++
+			object.SetName(fmt.Sprintf("%s%d", object.GetGenerateName(), generated))
+		}
+		return false, nil, nil
+	})
+	client.AddReactor("list", "*", func(action core.Action) (bool, runtime.Object, error) {
+		kind, ok := kinds[action.GetResource().Resource]
+		if !ok {
+			return false, nil, nil
+		}
+		list, err := tracker.List(action.GetResource(), v1.SchemeGroupVersion.WithKind(kind), action.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return true, nil, err
+		}
+		selector := action.(core.ListAction).GetListRestrictions().Labels
+		var matched []runtime.Object
+		for _, item := range items {
+			if object, err := meta.Accessor(item); err == nil && selector.Matches(labels.Set(object.GetLabels())) {
+				matched = append(matched, item)
+			}
+		}
+		return true, list, meta.SetList(list, matched)
+	})
+	client.AddReactor("*", "*", core.ObjectReaction(tracker))
+	return client
+}
+
+func newDeployment(name string, replicas int32) *appsv1.Deployment {
+	selector := map[string]string{"app": name}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: selector}},
+		},
+		Status: appsv1.DeploymentStatus{Replicas: replicas},
+	}
+}
+
+func newTestPod(name, app string, age time.Duration) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         "default",
+		Name:              name,
+		Labels:            map[string]string{"app": app},
+		CreationTimestamp: metav1.Time{Time: time.Now().Add(-age)},
+	}}
+}
+
+// newTestCronHPA returns a CronHPA of the Deployment named name, created age
+// ago, whose cron fires every minute.
+func newTestCronHPA(name string, replicas int32, age time.Duration) *v1.CronHPA {
+	return &v1.CronHPA{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              name,
+			UID:               types.UID(name),
+			CreationTimestamp: metav1.Time{Time: time.Now().Add(-age)},
+		},
+		Spec: v1.CronHPASpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: name},
+			Crons:          []v1.Cron{{Schedule: "* * * * *", TargetReplicas: replicas}},
+		},
+	}
+}
+
+func TestScaleAttemptsCountedOnce(t *testing.T) {
+	tc := newTestController(t,
+		newDeployment("web", 3),
+		newTestPod("web-a", "web", 3*time.Hour),
+		newTestPod("web-b", "web", 2*time.Hour),
+		newTestPod("web-c", "web", time.Hour))
+	cronhpa := newTestCronHPA("web", 1, 90*time.Second)
+	cronhpa.Spec.Drain = &v1.DrainPolicy{}
+	tc.addCronHPA(t, cronhpa)
+	attempts := metrics.ScaleAttempts.WithLabelValues("default", "web")
+	initial := testutil.ToFloat64(attempts)
+
+	// The drain spans syncs, which are a single attempt
+	tc.syncAll(context.TODO(), 1)
+	if replicas := tc.getReplicas(t, "web"); replicas != 3 {
+		t.Errorf("expected 3 replicas while draining, got %d", replicas)
+	}
+	if count := testutil.ToFloat64(attempts) - initial; count != 0 {
+		t.Errorf("expected no attempt while draining, got %v", count)
+	}
+	cronhpa = tc.getCronHPA(t, "web")
+	if cronhpa.Status.Drain == nil {
+		t.Fatalf("expected draining pods in status, got %+v", cronhpa.Status)
+	}
+	for _, name := range cronhpa.Status.Drain.Pods {
+		pod := tc.getPod(t, name).DeepCopy()
+		pod.Annotations[v1.DrainSafeAnnotation] = "true"
+		if err := tc.kubeTracker.Update(podsResource, pod, pod.Namespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tc.syncAll(context.TODO(), 1)
+	if replicas := tc.getReplicas(t, "web"); replicas != 1 {
+		t.Errorf("expected 1 replica once drained, got %d", replicas)
+	}
+	if count := testutil.ToFloat64(attempts) - initial; count != 1 {
+		t.Errorf("expected 1 attempt once drained, got %v", count)
+	}
+
+	// Nothing is due until the next schedule
+	tc.getCronHPA(t, "web")
+	tc.syncAll(context.TODO(), 1)
+	if count := testutil.ToFloat64(attempts) - initial; count != 1 {
+		t.Errorf("expected 1 attempt after the schedule, got %v", count)
+	}
+}
+
+func TestHasSynced(t *testing.T) {
+	tc := newTestController(t)
+	cronhpasSynced, freezesSynced := false, false
+	tc.cronhpaListersSynced = []cache.InformerSynced{func() bool { return cronhpasSynced }}
+	tc.freezeListerSynced = func() bool { return freezesSynced }
+	// Conflicts are indexed on the informer not started
+	tc.conflicts, _ = conflict.NewIndex(nil, nil)
+
+	if tc.HasSynced() {
+		t.Error("expected not synced before informers")
+	}
+	cronhpasSynced = true
+	if tc.HasSynced() {
+		t.Error("expected not synced before the freeze informer")
+	}
+	freezesSynced = true
+	if !tc.HasSynced() {
+		t.Error("expected synced after all informers")
+	}
+}
+
+func TestCheckSync(t *testing.T) {
+	tc := newTestController(t)
+	if err := tc.CheckSync(); err != nil {
+		t.Errorf("expected no error before running, got %v", err)
+	}
+	tc.syncAll(context.TODO(), 1)
+	if err := tc.CheckSync(); err != nil {
+		t.Errorf("expected no error after a sync, got %v", err)
+	}
+	tc.setLastSyncTime(time.Now().Add(-syncStalledTimeout - time.Minute))
+	if err := tc.CheckSync(); err == nil {
+		t.Error("expected an error after the sync stalled")
+	}
+	tc.syncAll(context.TODO(), 1)
+	if err := tc.CheckSync(); err != nil {
+		t.Errorf("expected no error after the sync resumed, got %v", err)
+	}
+}
+
+func TestSyncAllControllerName(t *testing.T) {
+	for _, test := range []struct {
+		controllerName string
+		scaled         []string
+	}{
+		{v1.DefaultControllerName, []string{"web", "api"}},
+		{"canary", []string{"db"}},
+	} {
+		tc := newTestController(t, newDeployment("web", 3), newDeployment("api", 3), newDeployment("db", 3))
+		tc.controllerName = test.controllerName
+		tc.addCronHPA(t, newTestCronHPA("web", 1, 90*time.Second))
+		api := newTestCronHPA("api", 1, 90*time.Second)
+		api.Spec.ControllerName = v1.DefaultControllerName
+		tc.addCronHPA(t, api)
+		db := newTestCronHPA("db", 1, 90*time.Second)
+		db.Spec.ControllerName = "canary"
+		tc.addCronHPA(t, db)
+
+		tc.syncAll(context.TODO(), 1)
+		scaled, keys := sets.NewString(test.scaled...), sets.NewString()
+		for _, name := range test.scaled {
+			keys.Insert("default/" + name)
+		}
+		for _, name := range []string{"web", "api", "db"} {
+			expected := int32(3)
+			if scaled.Has(name) {
+				expected = 1
+			}
+			if replicas := tc.getReplicas(t, name); replicas != expected {
+				t.Errorf("%s: expected %s scaled to %d replicas, got %d", test.controllerName, name, expected, replicas)
+			}
+		}
+		if !tc.syncedCronHPAs.Equal(keys) {
+			t.Errorf("%s: expected synced cronhpas %v, got %v", test.controllerName, keys.List(), tc.syncedCronHPAs.List())
+		}
+	}
+}
@@ -47,31 +47,107 @@ var CRD = &extensionsobj.CustomResourceDefinition{
 	},
 }
 
+var ExecutionCRD = &extensionsobj.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{
+		Name: "cronhpaexecutions.extensions.tkestack.io",
+	},
+	TypeMeta: metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	},
+	Spec: extensionsobj.CustomResourceDefinitionSpec{
+		Group:   "extensions.tkestack.io",
+		Version: "v1",
+		Scope:   extensionsobj.ResourceScope("Namespaced"),
+		Names: extensionsobj.CustomResourceDefinitionNames{
+			Plural:   "cronhpaexecutions",
+			Singular: "cronhpaexecution",
+			Kind:     "CronHPAExecution",
+			ListKind: "CronHPAExecutionList",
+		},
+	},
+}
+
+var FreezeCRD = &extensionsobj.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{
+		Name: "scalingfreezes.extensions.tkestack.io",
+	},
+	TypeMeta: metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	},
+	Spec: extensionsobj.CustomResourceDefinitionSpec{
+		Group:   "extensions.tkestack.io",
+		Version: "v1",
+		Scope:   extensionsobj.ResourceScope("Cluster"),
+		Names: extensionsobj.CustomResourceDefinitionNames{
+			Plural:   "scalingfreezes",
+			Singular: "scalingfreeze",
+			Kind:     "ScalingFreeze",
+			ListKind: "ScalingFreezeList",
+		},
+	},
+}
+
+var PolicyCRD = &extensionsobj.CustomResourceDefinition{
+	ObjectMeta: metav1.ObjectMeta{
+		Name: "cronhpapolicies.extensions.tkestack.io",
+	},
+	TypeMeta: metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	},
+	Spec: extensionsobj.CustomResourceDefinitionSpec{
+		Group:   "extensions.tkestack.io",
+		Version: "v1",
+		Scope:   extensionsobj.ResourceScope("Namespaced"),
+		Names: extensionsobj.CustomResourceDefinitionNames{
+			Plural:   "cronhpapolicies",
+			Singular: "cronhpapolicy",
+			Kind:     "CronHPAPolicy",
+			ListKind: "CronHPAPolicyList",
+		},
+	},
+}
+
+// CRDs are all the CRDs served for CronHPA controller.
+var CRDs = []*extensionsobj.CustomResourceDefinition{CRD, ExecutionCRD, FreezeCRD, PolicyCRD}
+
+// EnsureCRDCreated creates or updates all CRDs in CRDs.
 func EnsureCRDCreated(client apiextensionsclient.Interface) (created bool, err error) {
+	for _, crd := range CRDs {
+		if err := ensureCRDCreated(client, crd); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func ensureCRDCreated(client apiextensionsclient.Interface, crd *extensionsobj.CustomResourceDefinition) error {
 	crdClient := client.ApiextensionsV1beta1().CustomResourceDefinitions()
-	presetCRD, err := crdClient.Get(CRD.Name, metav1.GetOptions{})
+	presetCRD, err := crdClient.Get(crd.Name, metav1.GetOptions{})
 	if err == nil {
-		if reflect.DeepEqual(presetCRD.Spec, CRD.Spec) {
-			klog.V(1).Infof("CRD %s already exists", CRD.Name)
+		if reflect.DeepEqual(presetCRD.Spec, crd.Spec) {
+			klog.V(1).Infof("CRD %s already exists", crd.Name)
 		} else {
-			klog.V(3).Infof("Update CRD %s: %+v -> %+v", CRD.Name, presetCRD.Spec, CRD.Spec)
-			newCRD := CRD
+			klog.V(3).Infof("Update CRD %s: %+v -> %+v", crd.Name, presetCRD.Spec, crd.Spec)
+			newCRD := crd
 			newCRD.ResourceVersion = presetCRD.ResourceVersion
 			// Update CRD
 			if _, err := crdClient.Update(newCRD); err != nil {
-				klog.Errorf("Error update CRD %s: %v", CRD.Name, err)
-				return false, err
+				klog.Errorf("Error update CRD %s: %v", crd.Name, err)
+				return err
 			}
-			klog.V(1).Infof("Update CRD %s successfully.", CRD.Name)
+			klog.V(1).Infof("Update CRD %s successfully.", crd.Name)
 		}
 	} else {
 		// If not exist, create a new one
-		if _, err := crdClient.Create(CRD); err != nil {
-			klog.Errorf("Error creating CRD %s: %v", CRD.Name, err)
-			return false, err
+		if _, err := crdClient.Create(crd); err != nil {
+			klog.Errorf("Error creating CRD %s: %v", crd.Name, err)
+			return err
 		}
-		klog.V(1).Infof("Create CRD %s successfully.", CRD.Name)
+		klog.V(1).Infof("Create CRD %s successfully.", crd.Name)
 	}
 
-	return true, nil
+	return nil
 }
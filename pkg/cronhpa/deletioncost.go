@@ -0,0 +1,195 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+)
+
+const (
+	// podDeletionCostAnnotation is respected by ReplicaSets on scale-down, where
+	// pods with lower cost are removed first.
+	podDeletionCostAnnotation = "controller.kubernetes.io/pod-deletion-cost"
+	// deletionCostTimeout is the timeout of getting the cost of a pod by HTTP.
+	deletionCostTimeout = 5 * time.Second
+	// deletionCostWorkers is the number of pods whose cost is set concurrently.
+	deletionCostWorkers = 10
+)
+
+// deletionCostClient gets costs from pods. Their IPs are not in serving
+// certificates, and redirects are not followed, so that a pod can't send the
+// controller elsewhere.
+var deletionCostClient = &http.Client{Transport: newInsecureTransport(), CheckRedirect: noRedirect}
+
+// deletionCostSource returns the deletion cost of pods.
+type deletionCostSource interface {
+	cost(ctx context.Context, pod *corev1.Pod) (int32, error)
+}
+
+func newDeletionCostSource(spec *v1.PodDeletionCost, now time.Time) (deletionCostSource, error) {
+	switch spec.Source {
+	case v1.PodDeletionCostAge:
+		return &ageCostSource{now: now}, nil
+	case v1.PodDeletionCostAnnotation:
+		if spec.Annotation == "" {
+			return nil, fmt.Errorf("no annotation for the Annotation source")
+		}
+		return &annotationCostSource{annotation: spec.Annotation}, nil
+	case v1.PodDeletionCostHTTP:
+		if spec.HTTP == nil {
+			return nil, fmt.Errorf("no endpoint for the HTTP source")
+		}
+		return &httpCostSource{endpoint: spec.HTTP}, nil
+	}
+	return nil, fmt.Errorf("unsupported source %q", spec.Source)
+}
+
+// ageCostSource makes the oldest pods cost the least.
+type ageCostSource struct {
+	now time.Time
+}
+
+func (s *ageCostSource) cost(_ context.Context, pod *corev1.Pod) (int32, error) {
+	age := s.now.Sub(pod.CreationTimestamp.Time) / time.Second
+	if age > math.MaxInt32 {
+		age = math.MaxInt32
+	}
+	return -int32(age), nil
+}
+
+// annotationCostSource reads the cost from an annotation of pods.
+type annotationCostSource struct {
+	annotation string
+}
+
+func (s *annotationCostSource) cost(_ context.Context, pod *corev1.Pod) (int32, error) {
+	value, ok := pod.Annotations[s.annotation]
+	if !ok {
+		return 0, fmt.Errorf("no annotation %s", s.annotation)
+	}
+	cost, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid annotation %s: %v", s.annotation, err)
+	}
+	return int32(cost), nil
+}
+
+// httpCostSource gets the cost from an HTTP endpoint of pods.
+type httpCostSource struct {
+	endpoint *v1.HTTPDeletionCost
+}
+
+func (s *httpCostSource) cost(ctx context.Context, pod *corev1.Pod) (int32, error) {
+	if pod.Status.PodIP == "" {
+		return 0, fmt.Errorf("no pod IP")
+	}
+	scheme := strings.ToLower(string(s.endpoint.Scheme))
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := s.endpoint.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(s.endpoint.Port))), path)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, deletionCostTimeout)
+	defer cancel()
+	resp, err := deletionCostClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	cost, err := strconv.ParseInt(strings.TrimSpace(string(body)), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cost from %s: %v", url, err)
+	}
+	return int32(cost), nil
+}
+
+// setDeletionCosts sets the deletion cost annotation on pods of cronhpa's target,
+// selected by the selector of its scale. Pods whose cost can't be got are left
+// as they are.
+func (c *Controller) setDeletionCosts(ctx context.Context, cronhpa *v1.CronHPA, scale *autoscalingv1.Scale) error {
+	source, err := newDeletionCostSource(cronhpa.Spec.PodDeletionCost, time.Now())
+	if err != nil {
+		return err
+	}
+	if scale.Status.Selector == "" {
+		return fmt.Errorf("no selector in the scale of %s", getScaleReference(cronhpa))
+	}
+	pods, err := c.kubeclientset.CoreV1().Pods(cronhpa.Namespace).List(metav1.ListOptions{LabelSelector: scale.Status.Selector})
+	if err != nil {
+		return err
+	}
+
+	var failed int32
+	parallelize(ctx, deletionCostWorkers, len(pods.Items), func(i int) {
+		pod := &pods.Items[i]
+		// Draining pods keep the lowest cost set by the drain
+		if pod.DeletionTimestamp != nil || pod.Annotations[v1.DrainingAnnotation] == "true" {
+			return
+		}
+		cost, err := source.cost(ctx, pod)
+		if err != nil {
+			klog.V(2).Infof("Failed to get deletion cost of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			atomic.AddInt32(&failed, 1)
+			return
+		}
+		value := strconv.Itoa(int(cost))
+		if pod.Annotations[podDeletionCostAnnotation] == value {
+			return
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`, podDeletionCostAnnotation, value)
+		if _, err := c.kubeclientset.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.MergePatchType, []byte(patch)); err != nil {
+			klog.V(2).Infof("Failed to set deletion cost of pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			atomic.AddInt32(&failed, 1)
+		}
+	})
+	if failed > 0 {
+		return fmt.Errorf("failed to set deletion cost of %d of %d pods", failed, len(pods.Items))
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+// defaultDrainTimeout is the max time to wait for draining pods by default.
+const defaultDrainTimeout = time.Hour
+
+// drain drains surplus pods of cronhpa's target before scaling it down to
+// replicas by action, and returns true once they are drained or the drain times
+// out. The state is kept in status.drain across syncs, and the Draining
+// condition reports the progress.
+func (c *Controller) drain(cronhpa *v1.CronHPA, action *scaleAction, replicas int32) (bool, error) {
+	status := cronhpa.Status.Drain
+	if status != nil && (!status.ScheduledTime.Time.Equal(action.scheduledTime) || status.TargetReplicas != replicas) {
+		c.cancelDrain(cronhpa)
+		status = nil
+	}
+
+	if status == nil {
+		pods, err := c.startDrain(cronhpa, action.scale, replicas)
+		if err != nil {
+			c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedDrain", err.Error())
+			return false, fmt.Errorf("failed to drain pods: %v", err)
+		}
+		now := metav1.Now()
+		cronhpa.Status.Drain = &v1.DrainStatus{
+			ScheduledTime:  metav1.Time{Time: action.scheduledTime},
+			TargetReplicas: replicas,
+			StartTime:      now,
+			Pods:           pods,
+		}
+		message := fmt.Sprintf("Draining %d pods before scaling down to %d replicas: %s", len(pods), replicas, strings.Join(pods, ", "))
+		c.recorder.Event(cronhpa, corev1.EventTypeNormal, "DrainStarted", message)
+		setCondition(&cronhpa.Status, v1.Draining, corev1.ConditionTrue, "DrainStarted", message)
+		return len(pods) == 0, nil
+	}
+
+	safe, err := c.countSafePods(cronhpa.Namespace, action.scale.Status.Selector, status.Pods)
+	if err != nil {
+		return false, fmt.Errorf("failed to check draining pods: %v", err)
+	}
+	status.SafePods = safe
+	if int(safe) == len(status.Pods) {
+		message := fmt.Sprintf("All %d draining pods are safe to remove, scaling down to %d replicas", safe, replicas)
+		c.recorder.Event(cronhpa, corev1.EventTypeNormal, "DrainCompleted", message)
+		setCondition(&cronhpa.Status, v1.Draining, corev1.ConditionFalse, "DrainCompleted", message)
+		cronhpa.Status.Drain = nil
+		return true, nil
+	}
+	timeout := defaultDrainTimeout
+	if cronhpa.Spec.Drain.TimeoutSeconds != nil {
+		timeout = time.Duration(*cronhpa.Spec.Drain.TimeoutSeconds) * time.Second
+	}
+	if time.Since(status.StartTime.Time) > timeout {
+		message := fmt.Sprintf("Only %d of %d draining pods are safe to remove after %v, scaling down to %d replicas anyway",
+			safe, len(status.Pods), timeout, replicas)
+		c.recorder.Event(cronhpa, corev1.EventTypeWarning, "DrainTimeout", message)
+		setCondition(&cronhpa.Status, v1.Draining, corev1.ConditionFalse, "DrainTimeout", message)
+		cronhpa.Status.Drain = nil
+		return true, nil
+	}
+	setCondition(&cronhpa.Status, v1.Draining, corev1.ConditionTrue, "DrainInProgress",
+		fmt.Sprintf("%d of %d draining pods are safe to remove", safe, len(status.Pods)))
+	return false, nil
+}
+
+// startDrain annotates the surplus pods of scale beyond replicas as draining,
+// and returns their names. They also get the lowest deletion cost, so that the
+// ReplicaSet removes them on the scale-down.
+func (c *Controller) startDrain(cronhpa *v1.CronHPA, scale *autoscalingv1.Scale, replicas int32) ([]string, error) {
+	if scale.Status.Selector == "" {
+		return nil, fmt.Errorf("no selector in the scale of %s", getScaleReference(cronhpa))
+	}
+	podList, err := c.kubeclientset.CoreV1().Pods(cronhpa.Namespace).List(metav1.ListOptions{LabelSelector: scale.Status.Selector})
+	if err != nil {
+		return nil, err
+	}
+	var pods []*corev1.Pod
+	for i := range podList.Items {
+		if podList.Items[i].DeletionTimestamp == nil {
+			pods = append(pods, &podList.Items[i])
+		}
+	}
+	sortPodsToRemove(pods)
+
+	surplus := int(scale.Spec.Replicas - replicas)
+	if surplus > len(pods) {
+		surplus = len(pods)
+	}
+	var names []string
+	for _, pod := range pods[:surplus] {
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"true",%q:%q}}}`,
+			v1.DrainingAnnotation, podDeletionCostAnnotation, strconv.Itoa(math.MinInt32))
+		if _, err := c.kubeclientset.CoreV1().Pods(pod.Namespace).Patch(pod.Name, types.MergePatchType, []byte(patch)); err != nil {
+			return nil, fmt.Errorf("failed to annotate pod %s: %v", pod.Name, err)
+		}
+		names = append(names, pod.Name)
+	}
+	return names, nil
+}
+
+// cancelDrain removes the draining annotation from pods of the ongoing drain,
+// which is superseded, e.g. by a scale-up.
+func (c *Controller) cancelDrain(cronhpa *v1.CronHPA) {
+	status := cronhpa.Status.Drain
+	for _, name := range status.Pods {
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null}}}`, v1.DrainingAnnotation, podDeletionCostAnnotation)
+		_, err := c.kubeclientset.CoreV1().Pods(cronhpa.Namespace).Patch(name, types.MergePatchType, []byte(patch))
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("Failed to remove draining annotation from pod %s/%s: %v", cronhpa.Namespace, name, err)
+		}
+	}
+	message := fmt.Sprintf("Cancelled draining %d pods for scaling down to %d replicas", len(status.Pods), status.TargetReplicas)
+	c.recorder.Event(cronhpa, corev1.EventTypeNormal, "DrainCancelled", message)
+	setCondition(&cronhpa.Status, v1.Draining, corev1.ConditionFalse, "DrainCancelled", message)
+	cronhpa.Status.Drain = nil
+}
+
+// countSafePods returns the number of pods in names which are safe to remove
+// or already gone. Pods are listed by selector of the target's scale, and those
+// which no longer match it are gone from the target.
+func (c *Controller) countSafePods(namespace, selector string, names []string) (int32, error) {
+	if selector == "" {
+		return 0, fmt.Errorf("no selector in the scale")
+	}
+	podList, err := c.kubeclientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, err
+	}
+	remaining := sets.NewString(names...)
+	var safe int32
+	for _, pod := range podList.Items {
+		if !remaining.Has(pod.Name) {
+			continue
+		}
+		remaining.Delete(pod.Name)
+		if pod.DeletionTimestamp != nil || pod.Annotations[v1.DrainSafeAnnotation] == "true" {
+			safe++
+		}
+	}
+	return safe + int32(remaining.Len()), nil
+}
+
+// sortPodsToRemove sorts pods in the order their controller removes them on
+// scale-down: the highest ordinals of a StatefulSet, or otherwise the lowest
+// deletion cost and the newest.
+func sortPodsToRemove(pods []*corev1.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		if isStatefulSetPod(pods[i]) && isStatefulSetPod(pods[j]) {
+			return getOrdinal(pods[i]) > getOrdinal(pods[j])
+		}
+		if ci, cj := getDeletionCost(pods[i]), getDeletionCost(pods[j]); ci != cj {
+			return ci < cj
+		}
+		return pods[i].CreationTimestamp.After(pods[j].CreationTimestamp.Time)
+	})
+}
+
+func isStatefulSetPod(pod *corev1.Pod) bool {
+	owner := metav1.GetControllerOf(pod)
+	return owner != nil && owner.Kind == "StatefulSet"
+}
+
+// getOrdinal returns the ordinal of a StatefulSet pod, or -1 if invalid.
+func getOrdinal(pod *corev1.Pod) int {
+	i := strings.LastIndex(pod.Name, "-")
+	if i < 0 {
+		return -1
+	}
+	ordinal, err := strconv.Atoi(pod.Name[i+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+func getDeletionCost(pod *corev1.Pod) int64 {
+	cost, err := strconv.ParseInt(pod.Annotations[podDeletionCostAnnotation], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return cost
+}
@@ -0,0 +1,196 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSortPodsToRemove(t *testing.T) {
+	now := time.Now()
+	newPod := func(name string, owner string, cost string, age time.Duration) *corev1.Pod {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			CreationTimestamp: metav1.Time{Time: now.Add(-age)},
+		}}
+		if owner != "" {
+			controller := true
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "app", Controller: &controller}}
+		}
+		if cost != "" {
+			pod.Annotations = map[string]string{podDeletionCostAnnotation: cost}
+		}
+		return pod
+	}
+	for _, tc := range []struct {
+		name     string
+		pods     []*corev1.Pod
+		expected []string
+	}{
+		{
+			name: "statefulset",
+			pods: []*corev1.Pod{
+				newPod("app-2", "StatefulSet", "", time.Hour),
+				newPod("app-10", "StatefulSet", "", 3*time.Hour),
+				newPod("app-0", "StatefulSet", "", 2*time.Hour),
+			},
+			expected: []string{"app-10", "app-2", "app-0"},
+		},
+		{
+			name: "replicaset",
+			pods: []*corev1.Pod{
+				newPod("old", "ReplicaSet", "", 2*time.Hour),
+				newPod("new", "ReplicaSet", "", time.Hour),
+				newPod("cheap", "ReplicaSet", "-5", 3*time.Hour),
+				newPod("costly", "ReplicaSet", "5", 0),
+			},
+			expected: []string{"cheap", "new", "old", "costly"},
+		},
+	} {
+		sortPodsToRemove(tc.pods)
+		var names []string
+		for _, pod := range tc.pods {
+			names = append(names, pod.Name)
+		}
+		if !reflect.DeepEqual(names, tc.expected) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, names)
+		}
+	}
+}
+
+func TestDrain(t *testing.T) {
+	tc := newTestController(t,
+		newTestPod("web-a", "web", 3*time.Hour),
+		newTestPod("web-b", "web", 2*time.Hour),
+		newTestPod("web-c", "web", time.Hour),
+		newTestPod("db-a", "db", 0))
+	cronhpa := newTestCronHPA("web", 1, time.Hour)
+	timeout := int32(600)
+	cronhpa.Spec.Drain = &v1.DrainPolicy{TimeoutSeconds: &timeout}
+	action := &scaleAction{
+		cronhpa:       cronhpa,
+		scheduledTime: time.Now(),
+		scale: &autoscalingv1.Scale{
+			Spec:   autoscalingv1.ScaleSpec{Replicas: 3},
+			Status: autoscalingv1.ScaleStatus{Replicas: 3, Selector: "app=web"},
+		},
+	}
+	drain := func(replicas int32, expected bool, reason string, events ...string) {
+		t.Helper()
+		drained, err := tc.drain(cronhpa, action, replicas)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if drained != expected {
+			t.Errorf("expected drained %v, got %v", expected, drained)
+		}
+		if condition := getCondition(&cronhpa.Status, v1.Draining); condition == nil || condition.Reason != reason {
+			t.Errorf("expected condition reason %s, got %+v", reason, condition)
+		}
+		if actual := tc.events(); !reflect.DeepEqual(actual, events) {
+			t.Errorf("expected events %v, got %v", events, actual)
+		}
+	}
+	expectDraining := func(names ...string) {
+		t.Helper()
+		draining := map[string]bool{}
+		for _, name := range names {
+			draining[name] = true
+		}
+		for _, name := range []string{"web-a", "web-b", "web-c", "db-a"} {
+			obj, err := tc.kubeTracker.Get(podsResource, "default", name)
+			if err != nil {
+				continue
+			}
+			if annotated := obj.(*corev1.Pod).Annotations[v1.DrainingAnnotation] == "true"; annotated != draining[name] {
+				t.Errorf("expected pod %s draining %v, got %v", name, draining[name], annotated)
+			}
+		}
+	}
+	markSafe := func(name string) {
+		pod := tc.getPod(t, name).DeepCopy()
+		pod.Annotations[v1.DrainSafeAnnotation] = "true"
+		if err := tc.kubeTracker.Update(podsResource, pod, pod.Namespace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The newest pods are drained first
+	drain(1, false, "DrainStarted", "DrainStarted")
+	if expected := []string{"web-c", "web-b"}; cronhpa.Status.Drain == nil || !reflect.DeepEqual(cronhpa.Status.Drain.Pods, expected) {
+		t.Fatalf("expected draining pods %v, got %+v", expected, cronhpa.Status.Drain)
+	}
+	expectDraining("web-c", "web-b")
+
+	markSafe("web-c")
+	drain(1, false, "DrainInProgress")
+	if cronhpa.Status.Drain.SafePods != 1 {
+		t.Errorf("expected 1 safe pod, got %d", cronhpa.Status.Drain.SafePods)
+	}
+
+	// A pod no longer selected by the target is gone from it
+	pod := tc.getPod(t, "web-b").DeepCopy()
+	pod.Labels["app"] = "debug"
+	if err := tc.kubeTracker.Update(podsResource, pod, pod.Namespace); err != nil {
+		t.Fatal(err)
+	}
+	drain(1, true, "DrainCompleted", "DrainCompleted")
+	if cronhpa.Status.Drain != nil {
+		t.Errorf("expected drain done, got %+v", cronhpa.Status.Drain)
+	}
+
+	// The scale-down removes the pod
+	if err := tc.kubeTracker.Delete(podsResource, "default", "web-b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another scale-down cancels the drain and starts over
+	action.scheduledTime = action.scheduledTime.Add(time.Minute)
+	drain(2, false, "DrainStarted", "DrainStarted")
+	expectDraining("web-c")
+	action.scheduledTime = action.scheduledTime.Add(time.Minute)
+	drain(1, false, "DrainStarted", "DrainCancelled", "DrainStarted")
+	expectDraining("web-c", "web-a")
+
+	// Draining pods are removed anyway after the timeout
+	cronhpa.Status.Drain.StartTime.Time = time.Now().Add(-time.Duration(timeout+1) * time.Second)
+	drain(1, true, "DrainTimeout", "DrainTimeout")
+	if cronhpa.Status.Drain != nil {
+		t.Errorf("expected drain done, got %+v", cronhpa.Status.Drain)
+	}
+
+	// No pods to drain
+	action.scale.Spec.Replicas = 1
+	action.scheduledTime = action.scheduledTime.Add(time.Minute)
+	drain(1, true, "DrainStarted", "DrainStarted")
+}
+
+func TestCountSafePodsWithoutSelector(t *testing.T) {
+	tc := newTestController(t, newTestPod("web-a", "web", time.Hour))
+	if _, err := tc.countSafePods("default", "", []string{"web-a"}); err == nil {
+		t.Error("expected an error without a selector")
+	}
+}
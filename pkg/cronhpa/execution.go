@@ -0,0 +1,134 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"sort"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+)
+
+const (
+	// cronHPAUIDLabel is set on CronHPAExecutions to find the executions of a CronHPA.
+	// UID is used rather than name because label values are limited to 63 characters.
+	cronHPAUIDLabel = "extensions.tkestack.io/cronhpa-uid"
+
+	defaultSuccessfulHistoryLimit = 3
+	defaultFailedHistoryLimit     = 1
+)
+
+var controllerKind = v1.SchemeGroupVersion.WithKind("CronHPA")
+
+// recordExecution creates a CronHPAExecution for a scale attempt of cronhpa.
+func (c *Controller) recordExecution(cronhpa *v1.CronHPA, cron v1.Cron, scheduledTime, executionTime time.Time,
+	oldReplicas, newReplicas int32, scaleErr error) {
+	execution := &v1.CronHPAExecution{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    cronhpa.Name + "-",
+			Namespace:       cronhpa.Namespace,
+			Labels:          map[string]string{cronHPAUIDLabel: string(cronhpa.UID)},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cronhpa, controllerKind)},
+		},
+		Spec: v1.CronHPAExecutionSpec{
+			ScaleTargetRef: cronhpa.Spec.ScaleTargetRef,
+			Schedule:       cron.Schedule,
+			ScheduledTime:  metav1.Time{Time: scheduledTime},
+			TargetReplicas: cron.TargetReplicas,
+		},
+		Status: v1.CronHPAExecutionStatus{
+			ExecutionTime: metav1.Time{Time: executionTime},
+			OldReplicas:   oldReplicas,
+			NewReplicas:   newReplicas,
+			Result:        v1.ExecutionSucceeded,
+		},
+	}
+	if scaleErr != nil {
+		execution.Status.Result = v1.ExecutionFailed
+		execution.Status.Error = scaleErr.Error()
+	}
+
+	if _, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAExecutions(cronhpa.Namespace).Create(execution); err != nil {
+		klog.Errorf("Failed to create execution for cronhpa %s: %v", getCronHPAFullName(cronhpa), err)
+	}
+}
+
+// cleanupExecutions deletes the oldest executions of cronhpa beyond its history limits.
+func (c *Controller) cleanupExecutions(cronhpa *v1.CronHPA) {
+	selector := labels.SelectorFromSet(labels.Set{cronHPAUIDLabel: string(cronhpa.UID)})
+	executions, err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAExecutions(cronhpa.Namespace).List(
+		metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		klog.Errorf("Failed to list executions of cronhpa %s: %v", getCronHPAFullName(cronhpa), err)
+		return
+	}
+
+	var succeeded, failed []*v1.CronHPAExecution
+	for i := range executions.Items {
+		execution := &executions.Items[i]
+		if execution.Status.Result == v1.ExecutionFailed {
+			failed = append(failed, execution)
+		} else {
+			succeeded = append(succeeded, execution)
+		}
+	}
+
+	successfulLimit := int32(defaultSuccessfulHistoryLimit)
+	if cronhpa.Spec.SuccessfulHistoryLimit != nil {
+		successfulLimit = *cronhpa.Spec.SuccessfulHistoryLimit
+	}
+	failedLimit := int32(defaultFailedHistoryLimit)
+	if cronhpa.Spec.FailedHistoryLimit != nil {
+		failedLimit = *cronhpa.Spec.FailedHistoryLimit
+	}
+
+	for _, execution := range executionsToPrune(succeeded, successfulLimit) {
+		c.deleteExecution(execution)
+	}
+	for _, execution := range executionsToPrune(failed, failedLimit) {
+		c.deleteExecution(execution)
+	}
+}
+
+func (c *Controller) deleteExecution(execution *v1.CronHPAExecution) {
+	err := c.cronhpaclientset.CronhpacontrollerV1().CronHPAExecutions(execution.Namespace).Delete(execution.Name, nil)
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Failed to delete execution %s/%s: %v", execution.Namespace, execution.Name, err)
+		return
+	}
+	klog.V(4).Infof("Deleted execution %s/%s", execution.Namespace, execution.Name)
+}
+
+// executionsToPrune returns the oldest executions which exceed limit.
+func executionsToPrune(executions []*v1.CronHPAExecution, limit int32) []*v1.CronHPAExecution {
+	if limit < 0 {
+		limit = 0
+	}
+	if int32(len(executions)) <= limit {
+		return nil
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].Status.ExecutionTime.Before(&executions[j].Status.ExecutionTime)
+	})
+	return executions[:int32(len(executions))-limit]
+}
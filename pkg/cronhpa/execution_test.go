@@ -0,0 +1,139 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+func newExecution(name string, uid types.UID, result v1.ExecutionResult, executionTime time.Time) *v1.CronHPAExecution {
+	return &v1.CronHPAExecution{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "default",
+			Name:      name,
+			Labels:    map[string]string{cronHPAUIDLabel: string(uid)},
+		},
+		Status: v1.CronHPAExecutionStatus{
+			ExecutionTime: metav1.Time{Time: executionTime},
+			Result:        result,
+		},
+	}
+}
+
+func TestExecutionsToPrune(t *testing.T) {
+	now := time.Now()
+	newExecutions := func(ages ...int) []*v1.CronHPAExecution {
+		var executions []*v1.CronHPAExecution
+		for _, age := range ages {
+			executions = append(executions, newExecution(fmt.Sprintf("web-%d", age), "uid", v1.ExecutionSucceeded,
+				now.Add(-time.Duration(age)*time.Minute)))
+		}
+		return executions
+	}
+
+	tests := []struct {
+		name     string
+		ages     []int
+		limit    int32
+		expected []string
+	}{
+		{"none", nil, 3, nil},
+		{"within limit", []int{1, 2}, 3, nil},
+		{"at limit", []int{1, 2, 3}, 3, nil},
+		{"oldest first", []int{2, 5, 1, 4, 3}, 3, []string{"web-5", "web-4"}},
+		{"zero limit", []int{2, 1}, 0, []string{"web-2", "web-1"}},
+		{"negative limit", []int{2, 1}, -1, []string{"web-2", "web-1"}},
+	}
+	for _, test := range tests {
+		var names []string
+		for _, execution := range executionsToPrune(newExecutions(test.ages...), test.limit) {
+			names = append(names, execution.Name)
+		}
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("%s: expected to prune %v, got %v", test.name, test.expected, names)
+		}
+	}
+}
+
+func TestCleanupExecutions(t *testing.T) {
+	now := time.Now()
+	limit := func(n int32) *int32 { return &n }
+	cronhpa := &v1.CronHPA{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "new"}}
+
+	tests := []struct {
+		name             string
+		successfulLimit  *int32
+		failedLimit      *int32
+		expectedSurvivor []string
+	}{
+		{"default limits", nil, nil, []string{
+			"succeeded-1", "succeeded-2", "succeeded-3", "failed-1", "deleted-1", "deleted-2", "deleted-3",
+		}},
+		{"own limits", limit(1), limit(2), []string{
+			"succeeded-1", "failed-1", "failed-2", "deleted-1", "deleted-2", "deleted-3",
+		}},
+		{"no history", limit(0), limit(0), []string{"deleted-1", "deleted-2", "deleted-3"}},
+	}
+	for _, test := range tests {
+		client := newFakeClientset()
+		executions := client.CronhpacontrollerV1().CronHPAExecutions("default")
+		for i := 1; i <= 4; i++ {
+			age := -time.Duration(i) * time.Minute
+			for _, execution := range []*v1.CronHPAExecution{
+				newExecution(fmt.Sprintf("succeeded-%d", i), cronhpa.UID, v1.ExecutionSucceeded, now.Add(age)),
+				newExecution(fmt.Sprintf("failed-%d", i), cronhpa.UID, v1.ExecutionFailed, now.Add(age)),
+				// Left by a deleted CronHPA of the same name, which are
+				// collected by their owner references instead
+				newExecution(fmt.Sprintf("deleted-%d", i), "old", v1.ExecutionSucceeded, now.Add(age)),
+			} {
+				if i == 4 && execution.Labels[cronHPAUIDLabel] == "old" {
+					continue
+				}
+				if _, err := executions.Create(execution); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		cronhpa := cronhpa.DeepCopy()
+		cronhpa.Spec.SuccessfulHistoryLimit = test.successfulLimit
+		cronhpa.Spec.FailedHistoryLimit = test.failedLimit
+		c := &Controller{cronhpaclientset: client}
+		c.cleanupExecutions(cronhpa)
+
+		list, err := executions.List(metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		survivors := sets.NewString()
+		for _, execution := range list.Items {
+			survivors.Insert(execution.Name)
+		}
+		if expected := sets.NewString(test.expectedSurvivor...); !survivors.Equal(expected) {
+			t.Errorf("%s: expected executions %v, got %v", test.name, expected.List(), survivors.List())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/notify"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog"
+)
+
+// activeFreeze returns the ScalingFreeze holding back scheduled actions of
+// cronhpa at now, or nil if there is none.
+func (c *Controller) activeFreeze(cronhpa *v1.CronHPA, now time.Time) *v1.ScalingFreeze {
+	if c.freezeLister == nil {
+		return nil
+	}
+	freezes, err := c.freezeLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("Failed to list scalingfreezes: %v", err)
+		return nil
+	}
+	return selectFreeze(freezes, cronhpa, now)
+}
+
+// selectFreeze returns the freeze in freezes covering cronhpa at now. Of
+// several, those skipping actions take precedence over those deferring them,
+// and then the first by name.
+func selectFreeze(freezes []*v1.ScalingFreeze, cronhpa *v1.CronHPA, now time.Time) *v1.ScalingFreeze {
+	var selected *v1.ScalingFreeze
+	for _, freeze := range freezes {
+		if !isFrozen(freeze, cronhpa, now) {
+			continue
+		}
+		if selected == nil {
+			selected = freeze
+			continue
+		}
+		skip, selectedSkip := v1.GetFreezePolicy(freeze) == v1.FreezeSkip, v1.GetFreezePolicy(selected) == v1.FreezeSkip
+		if skip && !selectedSkip || skip == selectedSkip && freeze.Name < selected.Name {
+			selected = freeze
+		}
+	}
+	return selected
+}
+
+// isFrozen returns true if freeze covers cronhpa at now.
+func isFrozen(freeze *v1.ScalingFreeze, cronhpa *v1.CronHPA, now time.Time) bool {
+	if now.Before(freeze.Spec.Start.Time) || !now.Before(freeze.Spec.End.Time) {
+		return false
+	}
+	if len(freeze.Spec.Namespaces) > 0 {
+		found := false
+		for _, ns := range freeze.Spec.Namespaces {
+			if ns == cronhpa.Namespace {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if freeze.Spec.Selector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(freeze.Spec.Selector)
+	if err != nil {
+		klog.Errorf("Invalid selector of scalingfreeze %s: %v", freeze.Name, err)
+		return false
+	}
+	return selector.Matches(labels.Set(cronhpa.Labels))
+}
+
+// holdBack holds back action by freeze. With the Skip policy, the action is
+// done without scaling. Otherwise it stays due, and is applied on the first
+// sync after the freeze ends.
+func (c *Controller) holdBack(action *scaleAction, freeze *v1.ScalingFreeze, now time.Time) {
+	cronhpa, cron := action.cronhpa, action.cron
+	what := fmt.Sprintf("scaling to %d replicas by cron %s scheduled at %s", cron.TargetReplicas,
+		v1.GetCronName(&cron), action.scheduledTime.Format(time.RFC3339))
+	if action.manual == manualRestore {
+		what = fmt.Sprintf("restoring %d replicas after the override expired at %s", cron.TargetReplicas,
+			action.scheduledTime.Format(time.RFC3339))
+	}
+	why := fmt.Sprintf("frozen by scalingfreeze %s until %s", freeze.Name, freeze.Spec.End.Format(time.RFC3339))
+	if freeze.Spec.Reason != "" {
+		why += ": " + freeze.Spec.Reason
+	}
+
+	if v1.GetFreezePolicy(freeze) == v1.FreezeDefer {
+		if c.isNotified(notify.ScaleDeferred, cronhpa, cron, action.scheduledTime) {
+			return
+		}
+		message := fmt.Sprintf("Deferred %s, %s", what, why)
+		klog.Infof("%s: %s", getCronHPAFullName(cronhpa), message)
+		c.recorder.Event(cronhpa, corev1.EventTypeNormal, "DeferredRescale", message)
+		c.notify(notify.ScaleDeferred, cronhpa, cron, action.scheduledTime, 0, cron.TargetReplicas, message)
+		return
+	}
+
+	message := fmt.Sprintf("Skipped %s, %s", what, why)
+	klog.Infof("%s: %s", getCronHPAFullName(cronhpa), message)
+	c.recorder.Event(cronhpa, corev1.EventTypeNormal, "SkippedRescale", message)
+	c.notify(notify.ScaleSkipped, cronhpa, cron, action.scheduledTime, 0, cron.TargetReplicas, message)
+	if action.manual == manualRestore {
+		expireOverride(cronhpa, message)
+	} else {
+		cronhpa.Status.LastScheduleTime = &metav1.Time{Time: now}
+	}
+	if cronhpa.Status.Drain != nil {
+		// The scale-down being drained for is dropped
+		c.cancelDrain(cronhpa)
+	}
+	c.updateStatus(cronhpa)
+}
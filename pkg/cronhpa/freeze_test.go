@@ -0,0 +1,83 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSelectFreeze(t *testing.T) {
+	now := time.Now()
+	newFreeze := func(name string, start, end time.Duration, policy v1.FreezePolicy) *v1.ScalingFreeze {
+		return &v1.ScalingFreeze{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.ScalingFreezeSpec{
+				Start:  metav1.Time{Time: now.Add(start)},
+				End:    metav1.Time{Time: now.Add(end)},
+				Policy: policy,
+			},
+		}
+	}
+	cronhpa := &v1.CronHPA{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "default",
+		Name:      "web",
+		Labels:    map[string]string{"tier": "frontend"},
+	}}
+
+	otherNamespace := newFreeze("other-namespace", -time.Hour, time.Hour, v1.FreezeSkip)
+	otherNamespace.Spec.Namespaces = []string{"kube-system"}
+	otherLabels := newFreeze("other-labels", -time.Hour, time.Hour, v1.FreezeSkip)
+	otherLabels.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "backend"}}
+	matching := newFreeze("matching", -time.Hour, time.Hour, v1.FreezeDefer)
+	matching.Spec.Namespaces = []string{"default"}
+	matching.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "frontend"}}
+
+	tests := []struct {
+		name     string
+		freezes  []*v1.ScalingFreeze
+		expected string
+	}{
+		{"none", nil, ""},
+		{"past", []*v1.ScalingFreeze{newFreeze("past", -2*time.Hour, -time.Hour, v1.FreezeSkip)}, ""},
+		{"future", []*v1.ScalingFreeze{newFreeze("future", time.Hour, 2*time.Hour, v1.FreezeSkip)}, ""},
+		{"out of scope", []*v1.ScalingFreeze{otherNamespace, otherLabels}, ""},
+		{"in scope", []*v1.ScalingFreeze{otherNamespace, otherLabels, matching}, "matching"},
+		{"skip first", []*v1.ScalingFreeze{
+			newFreeze("a", -time.Hour, time.Hour, v1.FreezeDefer),
+			newFreeze("b", -time.Hour, time.Hour, ""),
+		}, "b"},
+		{"by name", []*v1.ScalingFreeze{
+			newFreeze("b", -time.Hour, time.Hour, v1.FreezeDefer),
+			newFreeze("a", -time.Hour, time.Hour, v1.FreezeDefer),
+		}, "a"},
+	}
+	for _, test := range tests {
+		var name string
+		if freeze := selectFreeze(test.freezes, cronhpa, now); freeze != nil {
+			name = freeze.Name
+		}
+		if name != test.expected {
+			t.Errorf("%s: expected freeze %q, got %q", test.name, test.expected, name)
+		}
+	}
+}
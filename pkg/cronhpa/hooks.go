@@ -0,0 +1,359 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+const (
+	// defaultHTTPHookTimeout is the timeout of HTTP hooks by default.
+	defaultHTTPHookTimeout = 30 * time.Second
+	// defaultJobHookTimeout is the timeout of Job hooks by default.
+	defaultJobHookTimeout = 10 * time.Minute
+	// maxHTTPHookTimeout bounds the timeout of HTTP hooks, which hold a
+	// connection meanwhile.
+	maxHTTPHookTimeout = 5 * time.Minute
+)
+
+var (
+	// hookClient calls HTTP hooks. Redirects are not followed, so that a
+	// hook can't send the controller elsewhere.
+	hookClient = &http.Client{CheckRedirect: noRedirect}
+	// insecureHookClient calls HTTP hooks with insecureSkipTLSVerify.
+	insecureHookClient = &http.Client{Transport: newInsecureTransport(), CheckRedirect: noRedirect}
+)
+
+// noRedirect makes an http.Client return redirects as responses.
+func noRedirect(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// newInsecureTransport returns a transport like the default one, which
+// doesn't verify server certificates.
+func newInsecureTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return transport
+}
+
+// httpHookKey is the key of the HTTP hook call in flight of a phase of a
+// cronhpa.
+type httpHookKey struct {
+	cronhpa string
+	phase   v1.HookPhase
+}
+
+// httpHookCall is an HTTP hook call, whose outcome is set once done is closed.
+type httpHookCall struct {
+	scheduledTime time.Time
+	done          chan struct{}
+	result        v1.HookResult
+	message       string
+}
+
+// hookAbortedError is returned by a failed hook with the Abort failure policy.
+type hookAbortedError struct {
+	phase   v1.HookPhase
+	message string
+}
+
+func (e *hookAbortedError) Error() string {
+	return fmt.Sprintf("%s hook failed: %s", e.phase, e.message)
+}
+
+// runHook runs hook of phase for action, which scales cronhpa's target from
+// current to target replicas, and returns true once the hook has finished.
+// Hooks run in the background, and are checked on later syncs with their
+// state in the status. The error is a *hookAbortedError if the hook failed
+// with the Abort failure policy.
+func (c *Controller) runHook(ctx context.Context, cronhpa *v1.CronHPA, action *scaleAction, phase v1.HookPhase,
+	hook *v1.ScaleHook, current, target int32) (bool, error) {
+	statusPtr := &cronhpa.Status.PreScaleHook
+	if phase == v1.PostScale {
+		statusPtr = &cronhpa.Status.PostScaleHook
+	}
+	status := *statusPtr
+	if status == nil || !status.ScheduledTime.Time.Equal(action.scheduledTime) {
+		if status != nil && status.Job != "" {
+			c.deleteHookJob(cronhpa, status.Job)
+		}
+		status = &v1.HookStatus{
+			ScheduledTime:   metav1.Time{Time: action.scheduledTime},
+			CurrentReplicas: current,
+			TargetReplicas:  target,
+			StartTime:       metav1.Now(),
+			Result:          v1.HookRunning,
+		}
+		*statusPtr = status
+		request := &v1.HookRequest{
+			Phase:           phase,
+			Namespace:       cronhpa.Namespace,
+			Name:            cronhpa.Name,
+			Schedule:        action.cron.Schedule,
+			ScheduledTime:   status.ScheduledTime,
+			CurrentReplicas: current,
+			TargetReplicas:  target,
+		}
+		if hook.HTTP != nil {
+			c.startHTTPHook(ctx, cronhpa, hook, request)
+			c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "HookStarted", "Started %s hook call", phase)
+		} else if hook.Job != nil {
+			job, err := c.createHookJob(cronhpa, hook.Job, request)
+			if err != nil {
+				c.finishHook(cronhpa, phase, status, v1.HookFailed, fmt.Sprintf("failed to create job: %v", err))
+			} else {
+				status.Job = job.Name
+				c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "HookStarted", "Started %s hook job %s", phase, job.Name)
+			}
+		} else {
+			c.finishHook(cronhpa, phase, status, v1.HookFailed, "neither http nor job is set")
+		}
+	} else if status.Result == v1.HookRunning {
+		if status.Job != "" {
+			c.checkHookJob(cronhpa, phase, hook, status)
+		} else {
+			c.checkHTTPHook(cronhpa, phase, status)
+		}
+	}
+
+	switch status.Result {
+	case v1.HookRunning:
+		return false, nil
+	case v1.HookSucceeded:
+		return true, nil
+	}
+	if hook.FailurePolicy == v1.HookFailurePolicyAbort {
+		return true, &hookAbortedError{phase: phase, message: status.Message}
+	}
+	return true, nil
+}
+
+// finishHook sets the result of a hook in status, and emits an event.
+func (c *Controller) finishHook(cronhpa *v1.CronHPA, phase v1.HookPhase, status *v1.HookStatus, result v1.HookResult, message string) {
+	status.Result = result
+	status.Message = message
+	status.CompletionTime = &metav1.Time{Time: time.Now()}
+	switch result {
+	case v1.HookSucceeded:
+		c.recorder.Eventf(cronhpa, corev1.EventTypeNormal, "HookSucceeded", "%s hook succeeded", phase)
+	case v1.HookTimedOut:
+		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "HookTimedOut", "%s hook timed out: %s", phase, message)
+	default:
+		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "HookFailed", "%s hook failed: %s", phase, message)
+	}
+}
+
+// startHTTPHook calls the HTTP hook of cronhpa with request in the
+// background, for checkHTTPHook to pick up the outcome.
+func (c *Controller) startHTTPHook(ctx context.Context, cronhpa *v1.CronHPA, hook *v1.ScaleHook, request *v1.HookRequest) {
+	timeout := defaultHTTPHookTimeout
+	if hook.TimeoutSeconds != nil {
+		timeout = time.Duration(*hook.TimeoutSeconds) * time.Second
+	}
+	// Rejected by the admission webhook, but CronHPAs may predate it
+	if timeout <= 0 || timeout > maxHTTPHookTimeout {
+		timeout = maxHTTPHookTimeout
+	}
+	call := &httpHookCall{scheduledTime: request.ScheduledTime.Time, done: make(chan struct{})}
+	c.httpHooks.Store(httpHookKey{cronhpa: getCronHPAFullName(cronhpa), phase: request.Phase}, call)
+	namespace := cronhpa.Namespace
+	go func() {
+		defer close(call.done)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		err := callHTTPHook(ctx, namespace, hook.HTTP, request)
+		switch {
+		case err == nil:
+			call.result = v1.HookSucceeded
+		case ctx.Err() == context.DeadlineExceeded:
+			call.result = v1.HookTimedOut
+			call.message = fmt.Sprintf("no response in %v", timeout)
+		default:
+			call.result = v1.HookFailed
+			call.message = err.Error()
+		}
+	}()
+}
+
+// checkHTTPHook finishes the running HTTP hook in status once its call has
+// returned.
+func (c *Controller) checkHTTPHook(cronhpa *v1.CronHPA, phase v1.HookPhase, status *v1.HookStatus) {
+	key := httpHookKey{cronhpa: getCronHPAFullName(cronhpa), phase: phase}
+	value, ok := c.httpHooks.Load(key)
+	if !ok || !value.(*httpHookCall).scheduledTime.Equal(status.ScheduledTime.Time) {
+		// The call was made by another replica or before a restart
+		c.finishHook(cronhpa, phase, status, v1.HookFailed, "the call was interrupted")
+		return
+	}
+	call := value.(*httpHookCall)
+	select {
+	case <-call.done:
+	default:
+		return
+	}
+	c.httpHooks.Delete(key)
+	c.finishHook(cronhpa, phase, status, call.result, call.message)
+}
+
+// forgetHTTPHooks drops the HTTP hook calls of a deleted cronhpa.
+func (c *Controller) forgetHTTPHooks(cronhpa string) {
+	c.httpHooks.Delete(httpHookKey{cronhpa: cronhpa, phase: v1.PreScale})
+	c.httpHooks.Delete(httpHookKey{cronhpa: cronhpa, phase: v1.PostScale})
+}
+
+// callHTTPHook posts request to the service of endpoint for a cronhpa in
+// namespace, and returns an error unless it responds 2xx before ctx is done.
+func callHTTPHook(ctx context.Context, namespace string, endpoint *v1.HTTPHook, request *v1.HookRequest) error {
+	// Rejected by the admission webhook, but CronHPAs may predate it
+	if endpoint.Namespace != "" && endpoint.Namespace != namespace {
+		return fmt.Errorf("service namespace %s is not the namespace of the cronhpa", endpoint.Namespace)
+	}
+	scheme := strings.ToLower(string(endpoint.Scheme))
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := endpoint.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	host := net.JoinHostPort(fmt.Sprintf("%s.%s.svc", endpoint.Name, namespace), strconv.Itoa(int(endpoint.Port)))
+	url := fmt.Sprintf("%s://%s%s", scheme, host, path)
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := hookClient
+	if endpoint.InsecureSkipTLSVerify {
+		client = insecureHookClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Drain the body so that the connection is reused, but don't keep it, as
+	// the status is readable by users who may not call the service
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// createHookJob creates a Job owned by cronhpa from template, whose containers
+// get request by environment variables.
+func (c *Controller) createHookJob(cronhpa *v1.CronHPA, template *batchv1beta1.JobTemplateSpec, request *v1.HookRequest) (*batchv1.Job, error) {
+	job := &batchv1.Job{
+		ObjectMeta: *template.ObjectMeta.DeepCopy(),
+		Spec:       *template.Spec.DeepCopy(),
+	}
+	job.Name = ""
+	job.GenerateName = fmt.Sprintf("%s-%s-", cronhpa.Name, strings.ToLower(string(request.Phase)))
+	job.Namespace = cronhpa.Namespace
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[cronHPAUIDLabel] = string(cronhpa.UID)
+	job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(cronhpa, controllerKind)}
+
+	env := []corev1.EnvVar{
+		{Name: "CRON_HPA_PHASE", Value: string(request.Phase)},
+		{Name: "CRON_HPA_NAMESPACE", Value: request.Namespace},
+		{Name: "CRON_HPA_NAME", Value: request.Name},
+		{Name: "CRON_HPA_SCHEDULE", Value: request.Schedule},
+		{Name: "CRON_HPA_SCHEDULED_TIME", Value: request.ScheduledTime.UTC().Format(time.RFC3339)},
+		{Name: "CRON_HPA_CURRENT_REPLICAS", Value: strconv.Itoa(int(request.CurrentReplicas))},
+		{Name: "CRON_HPA_TARGET_REPLICAS", Value: strconv.Itoa(int(request.TargetReplicas))},
+	}
+	for i := range job.Spec.Template.Spec.Containers {
+		container := &job.Spec.Template.Spec.Containers[i]
+		container.Env = append(container.Env, env...)
+	}
+	return c.kubeclientset.BatchV1().Jobs(cronhpa.Namespace).Create(job)
+}
+
+// checkHookJob finishes the running Job hook in status once its Job has
+// completed, failed or timed out.
+func (c *Controller) checkHookJob(cronhpa *v1.CronHPA, phase v1.HookPhase, hook *v1.ScaleHook, status *v1.HookStatus) {
+	job, err := c.kubeclientset.BatchV1().Jobs(cronhpa.Namespace).Get(status.Job, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		c.finishHook(cronhpa, phase, status, v1.HookFailed, fmt.Sprintf("job %s is gone", status.Job))
+		return
+	}
+	if err != nil {
+		klog.Errorf("Failed to get %s hook job %s/%s: %v", phase, cronhpa.Namespace, status.Job, err)
+		return
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			c.finishHook(cronhpa, phase, status, v1.HookSucceeded, "")
+			return
+		case batchv1.JobFailed:
+			c.finishHook(cronhpa, phase, status, v1.HookFailed, fmt.Sprintf("job %s failed: %s", job.Name, cond.Message))
+			return
+		}
+	}
+	timeout := defaultJobHookTimeout
+	if hook.TimeoutSeconds != nil {
+		timeout = time.Duration(*hook.TimeoutSeconds) * time.Second
+	}
+	if time.Since(status.StartTime.Time) > timeout {
+		c.deleteHookJob(cronhpa, job.Name)
+		c.finishHook(cronhpa, phase, status, v1.HookTimedOut, fmt.Sprintf("job %s didn't complete in %v", job.Name, timeout))
+	}
+}
+
+// deleteHookJob deletes a Job of a hook with its pods.
+func (c *Controller) deleteHookJob(cronhpa *v1.CronHPA, name string) {
+	propagation := metav1.DeletePropagationBackground
+	err := c.kubeclientset.BatchV1().Jobs(cronhpa.Namespace).Delete(name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Failed to delete hook job %s/%s: %v", cronhpa.Namespace, name, err)
+	}
+}
@@ -0,0 +1,53 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/notify"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// noticeUpcoming emits an UpcomingRescale event and notification of cron of
+// cronhpa, which fires at fireTime, if it's within spec.notifyBefore from now.
+// Each occurrence is noticed once.
+func (c *Controller) noticeUpcoming(ctx context.Context, cronhpa *v1.CronHPA, cron v1.Cron, scheduledTime, fireTime, now time.Time) {
+	if cronhpa.Spec.NotifyBefore == nil || fireTime.Sub(now) > cronhpa.Spec.NotifyBefore.Duration {
+		return
+	}
+	if isSkipNext(cronhpa, &cron) || c.isNotified(notify.ScaleUpcoming, cronhpa, cron, scheduledTime) {
+		return
+	}
+
+	change := fmt.Sprintf("to %d replicas", cron.TargetReplicas)
+	var current int32
+	if scale, _, err := c.getScale(ctx, cronhpa); err == nil {
+		current = scale.Spec.Replicas
+		change = fmt.Sprintf("from %d to %d replicas", current, cron.TargetReplicas)
+	}
+	message := fmt.Sprintf("Scaling %s %s at %s by cron %s, annotate %s=%s to skip it",
+		getScaleReference(cronhpa), change, fireTime.Format(time.RFC3339), v1.GetCronName(&cron),
+		v1.SkipNextAnnotation, v1.GetCronName(&cron))
+	c.recorder.Event(cronhpa, corev1.EventTypeNormal, "UpcomingRescale", message)
+	c.notify(notify.ScaleUpcoming, cronhpa, cron, scheduledTime, current, cron.TargetReplicas, message)
+}
@@ -0,0 +1,91 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNoticeUpcoming(t *testing.T) {
+	tc := newTestController(t, newDeployment("web", 3))
+	cronhpa := newTestCronHPA("web", 1, time.Hour)
+	cronhpa.Spec.Crons[0].Name = "scale-down"
+	cronhpa.Spec.NotifyBefore = &metav1.Duration{Duration: 15 * time.Minute}
+	cron := cronhpa.Spec.Crons[0]
+	now := time.Now()
+	notice := func(scheduledTime time.Time, events ...string) {
+		t.Helper()
+		tc.noticeUpcoming(context.TODO(), cronhpa, cron, scheduledTime, scheduledTime, now)
+		if actual := tc.events(); !reflect.DeepEqual(actual, events) {
+			t.Errorf("expected events %v, got %v", events, actual)
+		}
+	}
+
+	notice(now.Add(20 * time.Minute))
+	notice(now.Add(10*time.Minute), "UpcomingRescale")
+	// Each occurrence is noticed once
+	notice(now.Add(10 * time.Minute))
+	notice(now.Add(5*time.Minute), "UpcomingRescale")
+
+	cronhpa.Annotations = map[string]string{v1.SkipNextAnnotation: "scale-up"}
+	notice(now.Add(time.Minute), "UpcomingRescale")
+	cronhpa.Annotations = map[string]string{v1.SkipNextAnnotation: "scale-down"}
+	notice(now.Add(2 * time.Minute))
+
+	cronhpa.Annotations = nil
+	cronhpa.Spec.NotifyBefore = nil
+	notice(now.Add(3 * time.Minute))
+}
+
+func TestSkipNext(t *testing.T) {
+	tc := newTestController(t, newDeployment("web", 3))
+	cronhpa := newTestCronHPA("web", 1, 90*time.Second)
+	cronhpa.Spec.Crons[0].Name = "scale-down"
+	cronhpa.Annotations = map[string]string{v1.SkipNextAnnotation: "scale-down"}
+	tc.addCronHPA(t, cronhpa)
+
+	tc.syncAll(context.TODO(), 1)
+	if replicas := tc.getReplicas(t, "web"); replicas != 3 {
+		t.Errorf("expected the skipped schedule to leave 3 replicas, got %d", replicas)
+	}
+	if events, expected := tc.events(), []string{"SkippedRescale"}; !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected events %v, got %v", expected, events)
+	}
+	cronhpa = tc.getCronHPA(t, "web")
+	if _, ok := cronhpa.Annotations[v1.SkipNextAnnotation]; ok {
+		t.Errorf("expected %s removed, got %v", v1.SkipNextAnnotation, cronhpa.Annotations)
+	}
+	if cronhpa.Status.LastSkip == nil || cronhpa.Status.LastSkip.Cron != "scale-down" {
+		t.Errorf("expected the skip of scale-down in status, got %+v", cronhpa.Status.LastSkip)
+	}
+
+	// The next occurrence runs
+	cronhpa.Status.LastScheduleTime.Time = cronhpa.Status.LastScheduleTime.Add(-time.Minute)
+	tc.updateCronHPA(t, cronhpa)
+	tc.syncAll(context.TODO(), 1)
+	if replicas := tc.getReplicas(t, "web"); replicas != 1 {
+		t.Errorf("expected the next schedule to scale to 1 replica, got %d", replicas)
+	}
+}
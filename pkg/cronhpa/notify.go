@@ -0,0 +1,84 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/notify"
+)
+
+// notifiedKey is the key of the scheduled time of the latest event of a type
+// notified of a cron of a cronhpa.
+type notifiedKey struct {
+	cronhpa   string
+	cron      string
+	eventType notify.EventType
+}
+
+// notify sends a notification of a scale event of cronhpa for cron.
+func (c *Controller) notify(eventType notify.EventType, cronhpa *v1.CronHPA, cron v1.Cron, scheduledTime time.Time,
+	currentReplicas, targetReplicas int32, message string) {
+	if c.notifier == nil {
+		return
+	}
+	c.notifier.Notify(&notify.Event{
+		Type:            eventType,
+		Namespace:       cronhpa.Namespace,
+		Name:            cronhpa.Name,
+		Target:          cronhpa.Spec.ScaleTargetRef.Kind + "/" + cronhpa.Spec.ScaleTargetRef.Name,
+		Schedule:        cron.Schedule,
+		ScheduledTime:   scheduledTime,
+		CurrentReplicas: currentReplicas,
+		TargetReplicas:  targetReplicas,
+		Message:         message,
+		Time:            time.Now(),
+	})
+}
+
+// notifyOnce is notify, but only on the first call of eventType for each
+// schedule, e.g. of a failed scale which is retried on later syncs.
+func (c *Controller) notifyOnce(eventType notify.EventType, cronhpa *v1.CronHPA, cron v1.Cron, scheduledTime time.Time,
+	currentReplicas, targetReplicas int32, message string) {
+	if c.isNotified(eventType, cronhpa, cron, scheduledTime) {
+		return
+	}
+	c.notify(eventType, cronhpa, cron, scheduledTime, currentReplicas, targetReplicas, message)
+}
+
+// isNotified returns true if eventType has been notified of cron for
+// scheduledTime, and marks it notified otherwise.
+func (c *Controller) isNotified(eventType notify.EventType, cronhpa *v1.CronHPA, cron v1.Cron, scheduledTime time.Time) bool {
+	key := notifiedKey{cronhpa: getCronHPAFullName(cronhpa), cron: v1.GetCronName(&cron), eventType: eventType}
+	if notified, ok := c.notified.Load(key); ok && notified.(time.Time).Equal(scheduledTime) {
+		return true
+	}
+	c.notified.Store(key, scheduledTime)
+	return false
+}
+
+// forgetNotified drops the notified events of a deleted cronhpa.
+func (c *Controller) forgetNotified(cronhpa string) {
+	c.notified.Range(func(key, _ interface{}) bool {
+		if key.(notifiedKey).cronhpa == cronhpa {
+			c.notified.Delete(key)
+		}
+		return true
+	})
+}
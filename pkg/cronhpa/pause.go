@@ -0,0 +1,115 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/notify"
+
+	cronutil "github.com/robfig/cron"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// syncPause sets the Paused condition of cronhpa. It returns true if cronhpa
+// should not be planned on this sync, because scaling is paused, or because
+// its status has just been updated on resume.
+func (c *Controller) syncPause(cronhpa *v1.CronHPA, paused bool, reason string) bool {
+	condition := getCondition(&cronhpa.Status, v1.Paused)
+	wasPaused := condition != nil && condition.Status == corev1.ConditionTrue
+	switch {
+	case paused && (!wasPaused || condition.Message != reason):
+		setCondition(&cronhpa.Status, v1.Paused, corev1.ConditionTrue, "Paused", reason)
+		c.updateStatus(cronhpa)
+	case !paused && wasPaused:
+		// Missed schedules are planned on the next sync, since the resume
+		// time they are measured against is set here
+		setCondition(&cronhpa.Status, v1.Paused, corev1.ConditionFalse, "Resumed", "Scaling has resumed")
+		c.updateStatus(cronhpa)
+		return true
+	}
+	return paused
+}
+
+// planMissed handles the schedules of cronhpa which came due while scaling was
+// paused, by its MissedSchedulePolicy. It returns false if none was missed.
+// Otherwise it returns the action running the latest missed schedule, or nil
+// if they are skipped.
+func (c *Controller) planMissed(cronhpa *v1.CronHPA, latestScheduledTime time.Time, jitter time.Duration, now time.Time) (*scaleAction, bool) {
+	condition := getCondition(&cronhpa.Status, v1.Paused)
+	if condition == nil || condition.Status != corev1.ConditionFalse {
+		return nil, false
+	}
+	resumeTime := condition.LastTransitionTime.Time
+	cron, scheduledTime, missed := getLatestMissed(cronhpa, latestScheduledTime, jitter, resumeTime)
+	if !missed {
+		return nil, false
+	}
+
+	if v1.GetMissedSchedulePolicy(cronhpa) == v1.MissedScheduleSkip {
+		message := fmt.Sprintf("Skipped schedules missed while paused, the latest of which is scaling to %d replicas by cron %s scheduled at %s",
+			cron.TargetReplicas, v1.GetCronName(&cron), scheduledTime.Format(time.RFC3339))
+		klog.Infof("%s: %s", getCronHPAFullName(cronhpa), message)
+		c.recorder.Event(cronhpa, corev1.EventTypeNormal, "SkippedRescale", message)
+		c.notify(notify.ScaleSkipped, cronhpa, cron, scheduledTime, 0, cron.TargetReplicas, message)
+		// Schedules due since the resume still fire
+		cronhpa.Status.LastScheduleTime = &metav1.Time{Time: resumeTime}
+		if cronhpa.Status.Drain != nil {
+			c.cancelDrain(cronhpa)
+		}
+		c.updateStatus(cronhpa)
+		return nil, true
+	}
+	if c.skipNext(cronhpa, cron, scheduledTime, now) {
+		return nil, true
+	}
+	klog.Infof("%s: Running cron %s scheduled at %s, which was missed while paused",
+		getCronHPAFullName(cronhpa), v1.GetCronName(&cron), scheduledTime.Format(time.RFC3339))
+	return &scaleAction{cronhpa: cronhpa, cron: cron, scheduledTime: scheduledTime}, true
+}
+
+// getLatestMissed returns the cron of cronhpa with the latest occurrence
+// firing after the latest scheduled time and before the resume time, e.g. of
+// a pause or a deferring freeze, with
+// replicas resolved, and the time of that occurrence. It returns false if
+// there is none.
+func getLatestMissed(cronhpa *v1.CronHPA, latestScheduledTime time.Time, jitter time.Duration, resumeTime time.Time) (v1.Cron, time.Time, bool) {
+	var latest v1.Cron
+	var latestTime time.Time
+	for _, cron := range cronhpa.Spec.Crons {
+		sched, err := cronutil.ParseStandard(cron.Schedule)
+		if err != nil {
+			continue
+		}
+		replicas, ok := v1.GetCronReplicas(cronhpa, &cron)
+		if !ok {
+			continue
+		}
+		for t := sched.Next(latestScheduledTime); !t.IsZero() && t.Add(jitter).Before(resumeTime); t = sched.Next(t) {
+			if t.After(latestTime) {
+				latest, latestTime = cron, t
+				latest.TargetReplicas = replicas
+			}
+		}
+	}
+	return latest, latestTime, !latestTime.IsZero()
+}
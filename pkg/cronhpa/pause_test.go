@@ -0,0 +1,62 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+)
+
+func TestGetLatestMissed(t *testing.T) {
+	cronhpa := &v1.CronHPA{Spec: v1.CronHPASpec{
+		Profiles: map[string]v1.Profile{"peak": {Replicas: 20}},
+		Crons: []v1.Cron{
+			{Name: "morning", Schedule: "0 8 * * *", Profile: "peak"},
+			{Name: "evening", Schedule: "0 20 * * *", TargetReplicas: 4},
+		},
+	}}
+	day := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		name     string
+		latest   time.Time
+		resume   time.Time
+		cron     string
+		replicas int32
+	}{
+		{"none missed", day.Add(9 * time.Hour), day.Add(19 * time.Hour), "", 0},
+		{"one missed", day.Add(7 * time.Hour), day.Add(9 * time.Hour), "morning", 20},
+		{"latest of many", day.Add(7 * time.Hour), day.Add(24*time.Hour + 21*time.Hour), "evening", 4},
+		{"latest of many by profile", day.Add(7 * time.Hour), day.Add(24*time.Hour + 9*time.Hour), "morning", 20},
+	}
+	for _, test := range tests {
+		cron, _, missed := getLatestMissed(cronhpa, test.latest, 0, test.resume)
+		if test.cron == "" {
+			if missed {
+				t.Errorf("%s: expected none missed, got %s", test.name, cron.Name)
+			}
+			continue
+		}
+		if !missed || cron.Name != test.cron || cron.TargetReplicas != test.replicas {
+			t.Errorf("%s: expected cron %s to %d replicas, got %v %s to %d", test.name, test.cron, test.replicas,
+				missed, cron.Name, cron.TargetReplicas)
+		}
+	}
+}
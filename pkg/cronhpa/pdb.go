@@ -0,0 +1,103 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+	"math"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// applyPDB returns the replicas to scale cronhpa's target down to in this sync,
+// respecting PodDisruptionBudgets matching its pods, and whether the scale-down
+// is complete afterwards. The target replicas are raised to the largest integer
+// minAvailable, and other budgets limit each step to their disruptions allowed.
+// It sets the PDBLimited condition.
+func (c *Controller) applyPDB(cronhpa *v1.CronHPA, scale *autoscalingv1.Scale, replicas int32) (int32, bool, error) {
+	template, err := c.getPodTemplate(cronhpa)
+	if err != nil {
+		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedCheckPDB", err.Error())
+		return 0, false, fmt.Errorf("failed to check PodDisruptionBudgets: %v", err)
+	}
+	pdbs, err := c.kubeclientset.PolicyV1beta1().PodDisruptionBudgets(cronhpa.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedCheckPDB", err.Error())
+		return 0, false, fmt.Errorf("failed to list PodDisruptionBudgets: %v", err)
+	}
+
+	current := scale.Spec.Replicas
+	target := replicas
+	var floorPDB string
+	step := int32(math.MaxInt32)
+	var stepPDB string
+	for _, pdb := range pdbs.Items {
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || selector.Empty() || !selector.Matches(labels.Set(template.Labels)) {
+			continue
+		}
+		if pdb.Spec.MinAvailable != nil && pdb.Spec.MinAvailable.Type == intstr.Int {
+			if minAvailable := pdb.Spec.MinAvailable.IntVal; minAvailable > target {
+				target = minAvailable
+				floorPDB = pdb.Name
+			}
+			continue
+		}
+		if pdb.Status.PodDisruptionsAllowed < step {
+			step = pdb.Status.PodDisruptionsAllowed
+			stepPDB = pdb.Name
+		}
+	}
+	if target > current {
+		target = current
+	}
+
+	next := target
+	if stepPDB != "" && target < current {
+		if scale.Status.Replicas != current || step <= 0 {
+			// Wait for the previous step to finish, or for disruptions to be allowed
+			next = current
+		} else if current-step > target {
+			next = current - step
+		}
+	}
+
+	switch {
+	case next != target:
+		message := fmt.Sprintf("Scaling down to %d replicas in steps, now %d, as allowed by PodDisruptionBudget %s", target, next, stepPDB)
+		if next != current {
+			c.recorder.Event(cronhpa, corev1.EventTypeNormal, "SteppedScaleDown", message)
+		}
+		setCondition(&cronhpa.Status, v1.PDBLimited, corev1.ConditionTrue, "SteppedScaleDown", message)
+		return next, false, nil
+	case target != replicas:
+		message := fmt.Sprintf("Raised target replicas from %d to %d by minAvailable of PodDisruptionBudget %s", replicas, target, floorPDB)
+		c.recorder.Event(cronhpa, corev1.EventTypeWarning, "RaisedToMinAvailable", message)
+		setCondition(&cronhpa.Status, v1.PDBLimited, corev1.ConditionTrue, "RaisedToMinAvailable", message)
+		return target, true, nil
+	}
+	setCondition(&cronhpa.Status, v1.PDBLimited, corev1.ConditionFalse, "WithinPDB",
+		fmt.Sprintf("Scaled down to %d replicas within PodDisruptionBudgets", target))
+	return target, true, nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func newPDB(name, app string, minAvailable *intstr.IntOrString, allowed int32) *policyv1beta1.PodDisruptionBudget {
+	maxUnavailable := intstr.FromInt(1)
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": app}},
+			MinAvailable:   minAvailable,
+			MaxUnavailable: &maxUnavailable,
+		},
+		Status: policyv1beta1.PodDisruptionBudgetStatus{PodDisruptionsAllowed: allowed},
+	}
+	if minAvailable != nil {
+		pdb.Spec.MaxUnavailable = nil
+	}
+	return pdb
+}
+
+func TestApplyPDB(t *testing.T) {
+	three := intstr.FromInt(3)
+	half := intstr.FromString("50%")
+	tests := []struct {
+		name     string
+		pdbs     []runtime.Object
+		current  int32
+		observed int32
+		replicas int32
+		expected int32
+		complete bool
+		reason   string
+		events   []string
+	}{
+		{"no pdb", nil, 5, 5, 1, 1, true, "WithinPDB", nil},
+		{"other pods", []runtime.Object{newPDB("db", "db", &three, 0)}, 5, 5, 1, 1, true, "WithinPDB", nil},
+		{"min available", []runtime.Object{newPDB("web", "web", &three, 0)}, 5, 5, 1, 3, true, "RaisedToMinAvailable",
+			[]string{"RaisedToMinAvailable"}},
+		{"min available above current", []runtime.Object{newPDB("web", "web", &three, 0)}, 2, 2, 1, 2, true, "RaisedToMinAvailable",
+			[]string{"RaisedToMinAvailable"}},
+		{"first step", []runtime.Object{newPDB("web", "web", nil, 2)}, 5, 5, 1, 3, false, "SteppedScaleDown",
+			[]string{"SteppedScaleDown"}},
+		{"percent min available steps", []runtime.Object{newPDB("web", "web", &half, 1)}, 5, 5, 1, 4, false, "SteppedScaleDown",
+			[]string{"SteppedScaleDown"}},
+		{"previous step ongoing", []runtime.Object{newPDB("web", "web", nil, 2)}, 3, 4, 1, 3, false, "SteppedScaleDown", nil},
+		{"no disruptions allowed", []runtime.Object{newPDB("web", "web", nil, 0)}, 5, 5, 1, 5, false, "SteppedScaleDown", nil},
+		{"last step", []runtime.Object{newPDB("web", "web", nil, 2)}, 2, 2, 1, 1, true, "WithinPDB", nil},
+		{"smallest step", []runtime.Object{newPDB("web", "web", nil, 3), newPDB("all", "web", nil, 1)}, 5, 5, 1, 4, false,
+			"SteppedScaleDown", []string{"SteppedScaleDown"}},
+		{"floor with steps", []runtime.Object{newPDB("web", "web", nil, 3), newPDB("min", "web", &three, 0)}, 5, 5, 1, 3, true,
+			"RaisedToMinAvailable", []string{"RaisedToMinAvailable"}},
+	}
+	for _, test := range tests {
+		tc := newTestController(t, append(test.pdbs, newDeployment("web", test.current))...)
+		cronhpa := newTestCronHPA("web", test.replicas, time.Hour)
+		scale := &autoscalingv1.Scale{
+			Spec:   autoscalingv1.ScaleSpec{Replicas: test.current},
+			Status: autoscalingv1.ScaleStatus{Replicas: test.observed},
+		}
+		replicas, complete, err := tc.applyPDB(cronhpa, scale, test.replicas)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if replicas != test.expected || complete != test.complete {
+			t.Errorf("%s: expected %d replicas, complete %v, got %d, %v", test.name, test.expected, test.complete, replicas, complete)
+		}
+		condition := getCondition(&cronhpa.Status, v1.PDBLimited)
+		if condition == nil || condition.Reason != test.reason {
+			t.Errorf("%s: expected condition reason %s, got %+v", test.name, test.reason, condition)
+		} else if limited := test.reason != "WithinPDB"; (condition.Status == corev1.ConditionTrue) != limited {
+			t.Errorf("%s: expected condition %v, got %+v", test.name, limited, condition)
+		}
+		if events := tc.events(); !reflect.DeepEqual(events, test.events) {
+			t.Errorf("%s: expected events %v, got %v", test.name, test.events, events)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	"tkestack.io/cron-hpa/pkg/notify"
+	"tkestack.io/cron-hpa/pkg/policy"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+)
+
+// enforcePolicies returns true if action complies with the CronHPAPolicies of
+// its cronhpa, which may have changed since the cronhpa was admitted. A
+// violating action is refused and done without scaling. If the policies can't
+// be read, the action stays due and false is returned.
+func (c *Controller) enforcePolicies(action *scaleAction, now time.Time) bool {
+	cronhpa, cron := action.cronhpa, action.cron
+	policies, err := policy.Get(c.cronhpaclientset, cronhpa.Namespace, c.policyNamespace)
+	if err != nil {
+		klog.Errorf("Failed to get cronhpapolicies of %s: %v", getCronHPAFullName(cronhpa), err)
+		return false
+	}
+	if action.manual == manualOverride {
+		err = policy.ValidateReplicas(cron.TargetReplicas, policies)
+	} else {
+		err = policy.Validate(cronhpa, policies, now)
+	}
+	if err == nil {
+		return true
+	}
+
+	message := fmt.Sprintf("Refused scaling to %d replicas by cron %s: %v", cron.TargetReplicas, v1.GetCronName(&cron), err)
+	// The action is planned again if its status fails to update
+	if !c.isNotified(notify.ScaleSkipped, cronhpa, cron, action.scheduledTime) {
+		klog.Warningf("%s: %s", getCronHPAFullName(cronhpa), message)
+		c.recorder.Event(cronhpa, corev1.EventTypeWarning, "PolicyViolation", message)
+		c.notify(notify.ScaleSkipped, cronhpa, cron, action.scheduledTime, 0, cron.TargetReplicas, message)
+	}
+	if action.manual == "" {
+		cronhpa.Status.LastScheduleTime = &metav1.Time{Time: now}
+	} else {
+		c.finishManual(action, 0, 0, errors.New(message))
+	}
+	c.updateStatus(cronhpa)
+	return false
+}
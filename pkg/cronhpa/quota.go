@@ -0,0 +1,217 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"fmt"
+	"math"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// quotaRefusedError is returned if a scale-up is refused by QuotaPolicyRefuse.
+type quotaRefusedError struct {
+	message string
+}
+
+func (e *quotaRefusedError) Error() string {
+	return e.message
+}
+
+// applyQuotaPolicy returns the replicas to scale cronhpa's target to, given the
+// remaining ResourceQuota and cronhpa's quota policy, and sets the QuotaExceeded
+// condition. It returns a *quotaRefusedError if the scale-up is refused.
+func (c *Controller) applyQuotaPolicy(cronhpa *v1.CronHPA, scale *autoscalingv1.Scale, replicas int32) (int32, error) {
+	allowed, exhausted, err := c.fitQuota(cronhpa, scale, replicas)
+	if err != nil {
+		c.recorder.Eventf(cronhpa, corev1.EventTypeWarning, "FailedCheckQuota", err.Error())
+		return 0, fmt.Errorf("failed to check quota: %v", err)
+	}
+	if allowed >= replicas {
+		setCondition(&cronhpa.Status, v1.QuotaExceeded, corev1.ConditionFalse, "WithinQuota",
+			fmt.Sprintf("%d replicas fit ResourceQuota", replicas))
+		return replicas, nil
+	}
+
+	if cronhpa.Spec.QuotaPolicy == v1.QuotaPolicyRefuse {
+		message := fmt.Sprintf("Refused to scale to %d replicas, as only %d fit ResourceQuota: %s exhausted", replicas, allowed, exhausted)
+		c.recorder.Event(cronhpa, corev1.EventTypeWarning, "QuotaExceeded", message)
+		setCondition(&cronhpa.Status, v1.QuotaExceeded, corev1.ConditionTrue, "Refused", message)
+		return 0, &quotaRefusedError{message: fmt.Sprintf("only %d of %d replicas fit ResourceQuota: %s exhausted", allowed, replicas, exhausted)}
+	}
+	// Clamping never scales down
+	if allowed < scale.Spec.Replicas {
+		allowed = scale.Spec.Replicas
+	}
+	message := fmt.Sprintf("Clamped target replicas from %d to %d to fit ResourceQuota: %s exhausted", replicas, allowed, exhausted)
+	c.recorder.Event(cronhpa, corev1.EventTypeWarning, "QuotaExceeded", message)
+	setCondition(&cronhpa.Status, v1.QuotaExceeded, corev1.ConditionTrue, "Clamped", message)
+	return allowed, nil
+}
+
+// fitQuota returns the max replicas up to replicas of cronhpa's target which fit
+// the remaining ResourceQuota of the namespace, and which resource of which quota
+// is exhausted if not all replicas fit. Quotas with scopes are not considered.
+func (c *Controller) fitQuota(cronhpa *v1.CronHPA, scale *autoscalingv1.Scale, replicas int32) (int32, string, error) {
+	newPods := int64(replicas - scale.Status.Replicas)
+	if newPods <= 0 {
+		return replicas, "", nil
+	}
+	template, err := c.getPodTemplate(cronhpa)
+	if err != nil {
+		return 0, "", err
+	}
+	usage := podUsage(&template.Spec)
+
+	quotas, err := c.kubeclientset.CoreV1().ResourceQuotas(cronhpa.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return 0, "", err
+	}
+	fit := int64(math.MaxInt64)
+	var exhausted string
+	for _, quota := range quotas.Items {
+		if len(quota.Spec.Scopes) > 0 || quota.Spec.ScopeSelector != nil {
+			continue
+		}
+		for name, hard := range quota.Status.Hard {
+			perPod, ok := usage[name]
+			if !ok || perPod.IsZero() {
+				continue
+			}
+			used := quota.Status.Used[name]
+			n := (hard.MilliValue() - used.MilliValue()) / perPod.MilliValue()
+			if n < 0 {
+				n = 0
+			}
+			if n < fit {
+				fit = n
+				exhausted = fmt.Sprintf("%s of %s", name, quota.Name)
+			}
+		}
+	}
+	if fit >= newPods {
+		return replicas, "", nil
+	}
+	return scale.Status.Replicas + int32(fit), exhausted, nil
+}
+
+// getPodTemplate returns the pod template of cronhpa's target, e.g. a Deployment.
+func (c *Controller) getPodTemplate(cronhpa *v1.CronHPA) (*corev1.PodTemplateSpec, error) {
+	targetGV, err := schema.ParseGroupVersion(cronhpa.Spec.ScaleTargetRef.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API version in scale target reference: %v", err)
+	}
+	targetGK := schema.GroupKind{
+		Group: targetGV.Group,
+		Kind:  cronhpa.Spec.ScaleTargetRef.Kind,
+	}
+	mapping, err := c.restMapper.RESTMapping(targetGK, targetGV.Version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine resource for scale target reference: %v", err)
+	}
+	target, err := c.dynamicClient.Resource(mapping.Resource).Namespace(cronhpa.Namespace).Get(cronhpa.Spec.ScaleTargetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	object, found, err := unstructured.NestedMap(target.Object, "spec", "template")
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%s has no pod template", getScaleReference(cronhpa))
+	}
+	template := &corev1.PodTemplateSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, template); err != nil {
+		return nil, fmt.Errorf("invalid pod template of %s: %v", getScaleReference(cronhpa), err)
+	}
+	return template, nil
+}
+
+// podUsage returns the quota usage of a pod of spec, keyed by quota resource names.
+// Like the pod's effective requests, init containers count by their max, and a
+// limit without a request counts as the request. Defaults of LimitRanges are
+// not considered.
+func podUsage(spec *corev1.PodSpec) corev1.ResourceList {
+	requests, limits := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		containerRequests, containerLimits := containerResources(&container)
+		addResourceList(requests, containerRequests)
+		addResourceList(limits, containerLimits)
+	}
+	for _, container := range spec.InitContainers {
+		containerRequests, containerLimits := containerResources(&container)
+		maxResourceList(requests, containerRequests)
+		maxResourceList(limits, containerLimits)
+	}
+
+	usage := corev1.ResourceList{
+		corev1.ResourcePods:               *resource.NewQuantity(1, resource.DecimalSI),
+		corev1.ResourceName("count/pods"): *resource.NewQuantity(1, resource.DecimalSI),
+	}
+	for name, quantity := range requests {
+		usage[corev1.ResourceName("requests."+name)] = quantity
+		switch name {
+		case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+			usage[name] = quantity
+		}
+	}
+	for name, quantity := range limits {
+		usage[corev1.ResourceName("limits."+name)] = quantity
+	}
+	return usage
+}
+
+// containerResources returns the requests and limits of container, where a
+// limit without a request counts as the request.
+func containerResources(container *corev1.Container) (corev1.ResourceList, corev1.ResourceList) {
+	requests := corev1.ResourceList{}
+	for name, quantity := range container.Resources.Limits {
+		requests[name] = quantity
+	}
+	for name, quantity := range container.Resources.Requests {
+		requests[name] = quantity
+	}
+	return requests, container.Resources.Limits
+}
+
+func addResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; ok {
+			value.Add(quantity)
+			list[name] = value
+		} else {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
+}
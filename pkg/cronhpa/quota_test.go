@@ -0,0 +1,63 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestPodUsage(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+				Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+			}},
+			{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")},
+			}},
+		},
+		InitContainers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			}},
+		},
+	}
+	expected := map[corev1.ResourceName]string{
+		corev1.ResourcePods:           "1",
+		"count/pods":                  "1",
+		corev1.ResourceCPU:            "2",
+		corev1.ResourceRequestsCPU:    "2",
+		corev1.ResourceMemory:         "1Gi",
+		corev1.ResourceRequestsMemory: "1Gi",
+		corev1.ResourceLimitsCPU:      "1",
+		corev1.ResourceLimitsMemory:   "1Gi",
+	}
+	usage := podUsage(spec)
+	if len(usage) != len(expected) {
+		t.Errorf("expected %d resources, got %v", len(expected), usage)
+	}
+	for name, value := range expected {
+		if quantity, ok := usage[name]; !ok || quantity.Cmp(resource.MustParse(value)) != 0 {
+			t.Errorf("expected %s %s, got %v", name, value, usage[name])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	"golang.org/x/time/rate"
+)
+
+// ScaleRateLimit limits scale operations with token buckets, globally and per
+// namespace. Zero QPS means unlimited.
+type ScaleRateLimit struct {
+	QPS            float32
+	Burst          int
+	NamespaceQPS   float32
+	NamespaceBurst int
+}
+
+// scaleLimiter limits scale operations by ScaleRateLimit.
+type scaleLimiter struct {
+	config ScaleRateLimit
+	global *rate.Limiter
+
+	lock       sync.Mutex
+	namespaces map[string]*rate.Limiter
+}
+
+func newScaleLimiter(config ScaleRateLimit) *scaleLimiter {
+	l := &scaleLimiter{
+		config:     config,
+		namespaces: map[string]*rate.Limiter{},
+	}
+	if config.QPS > 0 {
+		l.global = rate.NewLimiter(rate.Limit(config.QPS), config.Burst)
+	}
+	return l
+}
+
+// wait blocks until a scale operation in namespace is allowed, or ctx is done.
+func (l *scaleLimiter) wait(ctx context.Context, namespace string) error {
+	// Wait for the namespace first, so that a busy namespace doesn't hold
+	// global tokens meanwhile.
+	if limiter := l.namespaceLimiter(namespace); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l.global != nil {
+		return l.global.Wait(ctx)
+	}
+	return nil
+}
+
+func (l *scaleLimiter) namespaceLimiter(namespace string) *rate.Limiter {
+	if l.config.NamespaceQPS <= 0 {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	limiter, ok := l.namespaces[namespace]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(l.config.NamespaceQPS), l.config.NamespaceBurst)
+		l.namespaces[namespace] = limiter
+	}
+	return limiter
+}
+
+// getJitter returns the delay of every schedule of cronhpa, which is derived from
+// its namespace and name, so that it is stable across syncs and replicas.
+func getJitter(cronhpa *v1.CronHPA) time.Duration {
+	if cronhpa.Spec.MaxJitterSeconds == nil || *cronhpa.Spec.MaxJitterSeconds <= 0 {
+		return 0
+	}
+	h := fnv.New64a()
+	h.Write([]byte(getCronHPAFullName(cronhpa)))
+	max := uint64(*cronhpa.Spec.MaxJitterSeconds) * uint64(time.Second/time.Millisecond)
+	return time.Duration(h.Sum64()%max) * time.Millisecond
+}
@@ -0,0 +1,46 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetJitter(t *testing.T) {
+	maxJitterSeconds := int32(60)
+	cronhpa := &v1.CronHPA{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "foo"},
+	}
+	if jitter := getJitter(cronhpa); jitter != 0 {
+		t.Errorf("expected no jitter without maxJitterSeconds, got %v", jitter)
+	}
+
+	cronhpa.Spec.MaxJitterSeconds = &maxJitterSeconds
+	jitter := getJitter(cronhpa)
+	if jitter < 0 || jitter >= time.Minute {
+		t.Errorf("expected jitter in [0, 1m), got %v", jitter)
+	}
+	if again := getJitter(cronhpa.DeepCopy()); again != jitter {
+		t.Errorf("expected stable jitter %v, got %v", jitter, again)
+	}
+}
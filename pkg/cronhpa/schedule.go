@@ -0,0 +1,99 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package cronhpa
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	cronutil "github.com/robfig/cron"
+)
+
+// ScheduledAction is an upcoming scale action of a CronHPA.
+type ScheduledAction struct {
+	Namespace      string    `json:"namespace"`
+	Name           string    `json:"name"`
+	Schedule       string    `json:"schedule"`
+	TargetReplicas int32     `json:"targetReplicas"`
+	Time           time.Time `json:"time"`
+}
+
+// scheduleTable holds the upcoming actions of all synced cronhpas.
+type scheduleTable struct {
+	lock    sync.RWMutex
+	actions map[string][]ScheduledAction
+}
+
+func newScheduleTable() *scheduleTable {
+	return &scheduleTable{actions: map[string][]ScheduledAction{}}
+}
+
+func (t *scheduleTable) set(key string, actions []ScheduledAction) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.actions[key] = actions
+}
+
+func (t *scheduleTable) delete(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.actions, key)
+}
+
+// list returns all actions sorted by time.
+func (t *scheduleTable) list() []ScheduledAction {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	var actions []ScheduledAction
+	for _, a := range t.actions {
+		actions = append(actions, a...)
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].Time.Before(actions[j].Time)
+	})
+	return actions
+}
+
+// getScheduledActions returns the next action of each schedule of cronhpa
+// after the latest scheduled time, delayed by its jitter.
+func getScheduledActions(cronhpa *v1.CronHPA) []ScheduledAction {
+	latestSchedledTime := getLatestScheduledTime(cronhpa)
+	jitter := getJitter(cronhpa)
+	var actions []ScheduledAction
+	for _, cron := range cronhpa.Spec.Crons {
+		sched, err := cronutil.ParseStandard(cron.Schedule)
+		if err != nil {
+			continue
+		}
+		replicas, ok := v1.GetCronReplicas(cronhpa, &cron)
+		if !ok {
+			continue
+		}
+		actions = append(actions, ScheduledAction{
+			Namespace:      cronhpa.Namespace,
+			Name:           cronhpa.Name,
+			Schedule:       cron.Schedule,
+			TargetReplicas: replicas,
+			Time:           sched.Next(latestSchedledTime).Add(jitter),
+		})
+	}
+	return actions
+}
@@ -0,0 +1,171 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package metrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "cronhpa"
+
+var (
+	// ScaleAttempts counts scale attempts of CronHPAs.
+	ScaleAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "scale_attempts_total",
+			Help:      "Number of scale attempts by CronHPA.",
+		},
+		[]string{"namespace", "cronhpa"},
+	)
+
+	// ScaleFailures counts failed scale attempts of CronHPAs.
+	ScaleFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "scale_failures_total",
+			Help:      "Number of failed scale attempts by CronHPA.",
+		},
+		[]string{"namespace", "cronhpa"},
+	)
+
+	// ScheduleLag observes the time between the scheduled and the actual fire time.
+	ScheduleLag = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "schedule_lag_seconds",
+			Help:      "Actual minus scheduled fire time of CronHPA schedules in seconds.",
+			Buckets:   []float64{1, 5, 10, 15, 30, 60, 120, 300, 600, 1800},
+		},
+		[]string{"namespace", "cronhpa"},
+	)
+
+	// NextFire is the time until the next schedule of a CronHPA fires.
+	NextFire = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "next_fire_seconds",
+			Help:      "Seconds until the next schedule of CronHPA fires.",
+		},
+		[]string{"namespace", "cronhpa"},
+	)
+
+	// SyncDuration observes the duration of sync loops.
+	SyncDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "sync_duration_seconds",
+			Help:      "Duration of syncing all CronHPAs in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+	)
+
+	// ManagedCronHPAs is the number of CronHPAs managed by the controller.
+	ManagedCronHPAs = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "managed_cronhpas",
+			Help:      "Number of CronHPAs managed by the controller.",
+		},
+	)
+
+	// ScaleQueueDelay observes the time scale operations wait for the rate limiter.
+	ScaleQueueDelay = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "scale_queue_delay_seconds",
+			Help:      "Time between planning a due scale operation and starting it in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+		},
+	)
+
+	// ShardMembers is the number of live controller replicas sharing CronHPAs.
+	ShardMembers = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "shard_members",
+			Help:      "Number of live controller replicas sharing CronHPAs.",
+		},
+	)
+
+	// Paused is 1 while all scaling is paused by the pause switch, and 0 otherwise.
+	Paused = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "paused",
+			Help:      "Whether all scaling is paused by the pause switch.",
+		},
+	)
+
+	// AdmissionDuration observes the latency of admission requests.
+	AdmissionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_duration_seconds",
+			Help:      "Latency of CronHPA admission requests in seconds.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		},
+		[]string{"operation"},
+	)
+
+	// AdmissionRejections counts rejected admission requests.
+	AdmissionRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "admission_rejections_total",
+			Help:      "Number of rejected CronHPA admission requests.",
+		},
+		[]string{"operation"},
+	)
+)
+
+var registerOnce sync.Once
+
+// Register registers all metrics to the default prometheus registry.
+func Register() {
+	registerOnce.Do(func() {
+		prometheus.MustRegister(ScaleAttempts)
+		prometheus.MustRegister(ScaleFailures)
+		prometheus.MustRegister(ScheduleLag)
+		prometheus.MustRegister(NextFire)
+		prometheus.MustRegister(SyncDuration)
+		prometheus.MustRegister(ManagedCronHPAs)
+		prometheus.MustRegister(ScaleQueueDelay)
+		prometheus.MustRegister(ShardMembers)
+		prometheus.MustRegister(Paused)
+		prometheus.MustRegister(AdmissionDuration)
+		prometheus.MustRegister(AdmissionRejections)
+	})
+}
+
+// Handler returns the http handler serving registered metrics.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// ForgetCronHPA deletes all series of a CronHPA which no longer exists.
+func ForgetCronHPA(namespace, name string) {
+	ScaleAttempts.DeleteLabelValues(namespace, name)
+	ScaleFailures.DeleteLabelValues(namespace, name)
+	ScheduleLag.DeleteLabelValues(namespace, name)
+	NextFire.DeleteLabelValues(namespace, name)
+}
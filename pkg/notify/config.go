@@ -0,0 +1,98 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notify
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Format is the payload format of a sink.
+type Format string
+
+const (
+	// FormatJSON posts an Event as is.
+	FormatJSON Format = "json"
+	// FormatSlack posts a Slack-compatible message, which is also accepted by
+	// many other chat tools.
+	FormatSlack Format = "slack"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultTimeout    = 10 * time.Second
+	defaultQueueSize  = 100
+)
+
+// Config is the notification config of the controller.
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// SinkConfig is a webhook notifications are posted to.
+type SinkConfig struct {
+	// Name of the sink in logs.
+	Name string `json:"name"`
+	// URL to post notifications to.
+	URL string `json:"url"`
+	// Format of the payload, json or slack. Defaults to json.
+	Format Format `json:"format,omitempty"`
+	// Events to send. Empty for all.
+	Events []EventType `json:"events,omitempty"`
+	// Namespaces of CronHPAs to send events of. Empty for all.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// SigningKeyFile contains the key to sign payloads with HMAC-SHA256, see
+	// SignatureHeader. Empty to not sign.
+	SigningKeyFile string `json:"signingKeyFile,omitempty"`
+	// MaxRetries of a failed post. Defaults to 3.
+	MaxRetries *int `json:"maxRetries,omitempty"`
+	// TimeoutSeconds of each post. Defaults to 10.
+	TimeoutSeconds *int `json:"timeoutSeconds,omitempty"`
+	// QueueSize is the max number of pending notifications. More are dropped.
+	// Defaults to 100.
+	QueueSize *int `json:"queueSize,omitempty"`
+}
+
+// LoadConfig reads a Config from a YAML or JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("invalid notification config %s: %v", path, err)
+	}
+	for i, sink := range config.Sinks {
+		if sink.Name == "" {
+			return nil, fmt.Errorf("no name of sink %d", i)
+		}
+		if sink.URL == "" {
+			return nil, fmt.Errorf("no url of sink %s", sink.Name)
+		}
+		switch sink.Format {
+		case "", FormatJSON, FormatSlack:
+		default:
+			return nil, fmt.Errorf("unsupported format %q of sink %s", sink.Format, sink.Name)
+		}
+	}
+	return config, nil
+}
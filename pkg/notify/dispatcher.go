@@ -0,0 +1,57 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notify
+
+import (
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Dispatcher sends events to all sinks of a Config.
+type Dispatcher struct {
+	webhooks []*webhook
+}
+
+// NewDispatcher creates a Dispatcher of config.
+func NewDispatcher(config *Config) (*Dispatcher, error) {
+	d := &Dispatcher{}
+	for _, sink := range config.Sinks {
+		w, err := newWebhook(sink)
+		if err != nil {
+			return nil, err
+		}
+		d.webhooks = append(d.webhooks, w)
+	}
+	return d, nil
+}
+
+// Notify queues event to all sinks.
+func (d *Dispatcher) Notify(event *Event) {
+	for _, w := range d.webhooks {
+		w.Notify(event)
+	}
+}
+
+// Run sends queued events until stopCh is closed.
+func (d *Dispatcher) Run(stopCh <-chan struct{}) {
+	var group wait.Group
+	for _, w := range d.webhooks {
+		w := w
+		group.Start(func() { w.run(stopCh) })
+	}
+	group.Wait()
+}
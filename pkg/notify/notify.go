@@ -0,0 +1,73 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package notify sends notifications of scale events of CronHPAs to external
+// sinks, e.g. chat webhooks.
+package notify
+
+import (
+	"time"
+)
+
+// EventType is the type of a notification event.
+type EventType string
+
+const (
+	// ScheduleFired is sent when a schedule is due, before its scale.
+	ScheduleFired EventType = "ScheduleFired"
+	// ScaleSucceeded is sent when a schedule has scaled its target.
+	ScaleSucceeded EventType = "ScaleSucceeded"
+	// ScaleFailed is sent when a schedule failed to scale its target.
+	ScaleFailed EventType = "ScaleFailed"
+	// ScaleSkipped is sent when a schedule is skipped without scaling.
+	ScaleSkipped EventType = "ScaleSkipped"
+	// ScaleUpcoming is sent ahead of a schedule.
+	ScaleUpcoming EventType = "ScaleUpcoming"
+	// ScaleDeferred is sent when a schedule is held back until a freeze ends.
+	ScaleDeferred EventType = "ScaleDeferred"
+)
+
+// Event is a scale event of a CronHPA.
+type Event struct {
+	Type      EventType `json:"type"`
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	// Target is the scale target, e.g. Deployment/web.
+	Target          string    `json:"target"`
+	Schedule        string    `json:"schedule"`
+	ScheduledTime   time.Time `json:"scheduledTime"`
+	CurrentReplicas int32     `json:"currentReplicas"`
+	TargetReplicas  int32     `json:"targetReplicas"`
+	// Message is a human readable description of the event.
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Notifier sends notifications of events. Notify should not block.
+type Notifier interface {
+	Notify(event *Event)
+}
+
+// Notifiers sends events to all of its notifiers.
+type Notifiers []Notifier
+
+// Notify sends event to all notifiers.
+func (n Notifiers) Notify(event *Event) {
+	for _, notifier := range n {
+		notifier.Notify(event)
+	}
+}
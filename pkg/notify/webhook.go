@@ -0,0 +1,178 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog"
+)
+
+// SignatureHeader is the header of the HMAC-SHA256 signature of a payload, in
+// the form of "sha256=<hex>".
+const SignatureHeader = "X-Cron-HPA-Signature"
+
+// initialBackoff is the delay before the first retry, doubled on each retry.
+var initialBackoff = time.Second
+
+// webhook posts events to a URL. It implements Notifier.
+type webhook struct {
+	config     SinkConfig
+	events     sets.String
+	namespaces sets.String
+	key        []byte
+	maxRetries int
+	client     *http.Client
+	queue      chan *Event
+}
+
+func newWebhook(config SinkConfig) (*webhook, error) {
+	w := &webhook{
+		config:     config,
+		events:     sets.NewString(),
+		namespaces: sets.NewString(config.Namespaces...),
+		maxRetries: defaultMaxRetries,
+		client:     &http.Client{Timeout: defaultTimeout},
+	}
+	for _, event := range config.Events {
+		w.events.Insert(string(event))
+	}
+	if config.SigningKeyFile != "" {
+		key, err := ioutil.ReadFile(config.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key of sink %s: %v", config.Name, err)
+		}
+		w.key = bytes.TrimSpace(key)
+	}
+	if config.MaxRetries != nil {
+		w.maxRetries = *config.MaxRetries
+	}
+	if config.TimeoutSeconds != nil {
+		w.client.Timeout = time.Duration(*config.TimeoutSeconds) * time.Second
+	}
+	queueSize := defaultQueueSize
+	if config.QueueSize != nil {
+		queueSize = *config.QueueSize
+	}
+	w.queue = make(chan *Event, queueSize)
+	return w, nil
+}
+
+// Notify queues event if the sink wants it.
+func (w *webhook) Notify(event *Event) {
+	if (w.events.Len() > 0 && !w.events.Has(string(event.Type))) ||
+		(w.namespaces.Len() > 0 && !w.namespaces.Has(event.Namespace)) {
+		return
+	}
+	select {
+	case w.queue <- event:
+	default:
+		klog.Warningf("Dropped %s notification of %s/%s to sink %s: queue is full", event.Type, event.Namespace, event.Name, w.config.Name)
+	}
+}
+
+// run posts queued events until stopCh is closed.
+func (w *webhook) run(stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case event := <-w.queue:
+			if err := w.send(event, stopCh); err != nil {
+				klog.Errorf("Failed to send %s notification of %s/%s to sink %s: %v", event.Type, event.Namespace, event.Name, w.config.Name, err)
+			}
+		}
+	}
+}
+
+// send posts event, and retries with exponential backoff on errors which
+// may be transient.
+func (w *webhook) send(event *Event, stopCh <-chan struct{}) error {
+	body, err := w.payload(event)
+	if err != nil {
+		return err
+	}
+	backoff := initialBackoff
+	for retry := 0; ; retry++ {
+		retriable, err := w.post(body)
+		if err == nil || !retriable || retry >= w.maxRetries {
+			return err
+		}
+		klog.V(4).Infof("Retrying notification to sink %s in %v: %v", w.config.Name, backoff, err)
+		select {
+		case <-stopCh:
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post posts body once, and returns whether a failure is worth retrying.
+func (w *webhook) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(w.key) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.key, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	message, _ := ioutil.ReadAll(resp.Body)
+	err = fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// payload returns the body to post of event in the format of the sink.
+func (w *webhook) payload(event *Event) ([]byte, error) {
+	if w.config.Format == FormatSlack {
+		return json.Marshal(map[string]string{"text": slackText(event)})
+	}
+	return json.Marshal(event)
+}
+
+// slackText returns a one line summary of event.
+func slackText(event *Event) string {
+	return fmt.Sprintf("*%s* CronHPA %s/%s (%s, schedule `%s`): %s",
+		event.Type, event.Namespace, event.Name, event.Target, event.Schedule, event.Message)
+}
+
+// Sign returns the signature of body with key in the form of SignatureHeader.
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,68 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package notify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWebhookSend(t *testing.T) {
+	initialBackoff = time.Millisecond
+	key := []byte("secret")
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := ioutil.WriteFile(keyFile, append(key, '\n'), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int
+	var received map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		body, _ := ioutil.ReadAll(r.Body)
+		if signature := r.Header.Get(SignatureHeader); signature != Sign(key, body) {
+			t.Errorf("unexpected signature %q", signature)
+		}
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.Unmarshal(body, &received)
+	}))
+	defer server.Close()
+
+	w, err := newWebhook(SinkConfig{Name: "test", URL: server.URL, Format: FormatSlack, SigningKeyFile: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := &Event{Type: ScaleFailed, Namespace: "default", Name: "web", Target: "Deployment/web", Schedule: "0 8 * * *", Message: "boom"}
+	if err := w.send(event, make(chan struct{})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+	if expected := slackText(event); received["text"] != expected {
+		t.Errorf("expected text %q, got %q", expected, received["text"])
+	}
+}
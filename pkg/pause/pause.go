@@ -0,0 +1,117 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package pause is an emergency switch pausing all scaling of the controller,
+// set by a well-known ConfigMap or a file.
+package pause
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+)
+
+const (
+	// PausedKey of the ConfigMap pauses scaling if it's "true".
+	PausedKey = "paused"
+	// ReasonKey of the ConfigMap is the reason of the pause.
+	ReasonKey = "reason"
+)
+
+// Switch tells whether scaling is paused. It's paused while the ConfigMap has
+// PausedKey set to "true", or the file exists, whose content is the reason.
+type Switch struct {
+	configMaps       corelisters.ConfigMapNamespaceLister
+	configMapsSynced cache.InformerSynced
+	configMapName    string
+	file             string
+}
+
+// New creates a Switch watching the ConfigMap namespace/name, if name is not
+// empty, and file, if not empty. The returned informer factory needs to be
+// started.
+func New(client kubernetes.Interface, namespace, name, file string) (*Switch, kubeinformers.SharedInformerFactory) {
+	s := &Switch{configMapName: name, file: file}
+	if name == "" {
+		return s, nil
+	}
+	factory := kubeinformers.NewSharedInformerFactoryWithOptions(client, 0,
+		kubeinformers.WithNamespace(namespace),
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+		}))
+	informer := factory.Core().V1().ConfigMaps()
+	s.configMaps = informer.Lister().ConfigMaps(namespace)
+	s.configMapsSynced = informer.Informer().HasSynced
+	return s, factory
+}
+
+// HasSynced returns true if the ConfigMap informer has synced.
+func (s *Switch) HasSynced() bool {
+	return s.configMapsSynced == nil || s.configMapsSynced()
+}
+
+// Paused returns true and the reason if scaling is paused.
+func (s *Switch) Paused() (bool, string) {
+	if s.file != "" {
+		data, err := ioutil.ReadFile(s.file)
+		if err == nil || !os.IsNotExist(err) {
+			if err != nil {
+				// It's there but unreadable, so err on the side of pausing
+				klog.Errorf("Failed to read pause file %s: %v", s.file, err)
+			}
+			reason := strings.TrimSpace(string(data))
+			if reason == "" {
+				reason = "Paused by " + s.file
+			}
+			return true, reason
+		}
+	}
+	if s.configMaps != nil {
+		configMap, err := s.configMaps.Get(s.configMapName)
+		if err != nil {
+			if !errors.IsNotFound(err) {
+				klog.Errorf("Failed to get pause configmap %s: %v", s.configMapName, err)
+			}
+			return false, ""
+		}
+		return isPausedBy(configMap)
+	}
+	return false, ""
+}
+
+// isPausedBy returns true and the reason if configMap pauses scaling.
+func isPausedBy(configMap *corev1.ConfigMap) (bool, string) {
+	if configMap.Data[PausedKey] != "true" {
+		return false, ""
+	}
+	reason := configMap.Data[ReasonKey]
+	if reason == "" {
+		reason = "Paused by configmap " + configMap.Namespace + "/" + configMap.Name
+	}
+	return true, reason
+}
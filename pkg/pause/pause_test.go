@@ -0,0 +1,51 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package pause
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPausedByFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pause")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "pause")
+	s, _ := New(nil, "", "", file)
+
+	if paused, _ := s.Paused(); paused {
+		t.Errorf("expected not paused without the file")
+	}
+	if err := ioutil.WriteFile(file, []byte("INC-42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if paused, reason := s.Paused(); !paused || reason != "INC-42" {
+		t.Errorf("expected paused by INC-42, got %v %q", paused, reason)
+	}
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if paused, reason := s.Paused(); !paused || reason != "Paused by "+file {
+		t.Errorf("expected paused by the file, got %v %q", paused, reason)
+	}
+}
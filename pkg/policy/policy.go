@@ -0,0 +1,166 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package policy checks CronHPAs against CronHPAPolicies, both on admission
+// and on execution.
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+	clientset "tkestack.io/cron-hpa/pkg/client/clientset/versioned"
+
+	cronutil "github.com/robfig/cron"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// intervalHorizon is how far ahead fires are checked against MinInterval,
+// which covers schedules of all days of a year.
+const intervalHorizon = 366 * 24 * time.Hour
+
+// Get returns the CronHPAPolicies of namespace, which are those in it, or
+// otherwise the defaults in defaultNamespace.
+func Get(client clientset.Interface, namespace, defaultNamespace string) ([]v1.CronHPAPolicy, error) {
+	list, err := client.CronhpacontrollerV1().CronHPAPolicies(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(list.Items) > 0 || namespace == defaultNamespace {
+		return list.Items, nil
+	}
+	list, err = client.CronhpacontrollerV1().CronHPAPolicies(defaultNamespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// Validate returns an error if cronhpa violates any of policies. Fires within
+// a year from now are checked against MinInterval.
+func Validate(cronhpa *v1.CronHPA, policies []v1.CronHPAPolicy, now time.Time) error {
+	for i := range policies {
+		if err := validate(cronhpa, &policies[i].Spec, now); err != nil {
+			return fmt.Errorf("cronhpapolicy %s/%s: %v", policies[i].Namespace, policies[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateReplicas returns an error if replicas violate any of policies.
+func ValidateReplicas(replicas int32, policies []v1.CronHPAPolicy) error {
+	for i := range policies {
+		if err := validateReplicas(replicas, &policies[i].Spec); err != nil {
+			return fmt.Errorf("cronhpapolicy %s/%s: %v", policies[i].Namespace, policies[i].Name, err)
+		}
+	}
+	return nil
+}
+
+func validate(cronhpa *v1.CronHPA, spec *v1.CronHPAPolicySpec, now time.Time) error {
+	if kinds := spec.AllowedTargetKinds; len(kinds) > 0 && !contains(kinds, cronhpa.Spec.ScaleTargetRef.Kind) {
+		return fmt.Errorf("target kind %s is not allowed", cronhpa.Spec.ScaleTargetRef.Kind)
+	}
+	if spec.MaxCrons != nil && len(cronhpa.Spec.Crons) > int(*spec.MaxCrons) {
+		return fmt.Errorf("%d crons exceed the max of %d", len(cronhpa.Spec.Crons), *spec.MaxCrons)
+	}
+	var allowedSchedules []string
+	for _, schedule := range spec.AllowedSchedules {
+		allowedSchedules = append(allowedSchedules, normalizeSchedule(schedule))
+	}
+	for i := range cronhpa.Spec.Crons {
+		cron := &cronhpa.Spec.Crons[i]
+		if len(allowedSchedules) > 0 && !contains(allowedSchedules, normalizeSchedule(cron.Schedule)) {
+			return fmt.Errorf("schedule %q of cron %s is not allowed", cron.Schedule, v1.GetCronName(cron))
+		}
+		if replicas, ok := v1.GetCronReplicas(cronhpa, cron); ok {
+			if err := validateReplicas(replicas, spec); err != nil {
+				return fmt.Errorf("cron %s: %v", v1.GetCronName(cron), err)
+			}
+		}
+	}
+	if spec.MinInterval != nil {
+		return validateInterval(cronhpa.Spec.Crons, spec.MinInterval.Duration, now)
+	}
+	return nil
+}
+
+func validateReplicas(replicas int32, spec *v1.CronHPAPolicySpec) error {
+	if spec.MaxReplicas != nil && replicas > *spec.MaxReplicas {
+		return fmt.Errorf("%d replicas exceed the max of %d", replicas, *spec.MaxReplicas)
+	}
+	if spec.MinReplicas != nil && replicas < *spec.MinReplicas {
+		return fmt.Errorf("%d replicas are below the min of %d", replicas, *spec.MinReplicas)
+	}
+	return nil
+}
+
+// validateInterval returns an error if any two fires of crons within
+// intervalHorizon from now are closer than minInterval.
+func validateInterval(crons []v1.Cron, minInterval time.Duration, now time.Time) error {
+	var scheds []cronutil.Schedule
+	var names []string
+	var next []time.Time
+	for i := range crons {
+		sched, err := cronutil.ParseStandard(crons[i].Schedule)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q of cron %s: %v", crons[i].Schedule, v1.GetCronName(&crons[i]), err)
+		}
+		scheds = append(scheds, sched)
+		names = append(names, v1.GetCronName(&crons[i]))
+		next = append(next, sched.Next(now))
+	}
+	end := now.Add(intervalHorizon)
+	var last time.Time
+	var lastName string
+	for {
+		// Merge the fires of all crons in time order
+		earliest := -1
+		for i, t := range next {
+			if !t.IsZero() && (earliest < 0 || t.Before(next[earliest])) {
+				earliest = i
+			}
+		}
+		if earliest < 0 || next[earliest].After(end) {
+			return nil
+		}
+		t := next[earliest]
+		if !last.IsZero() && t.Sub(last) < minInterval {
+			return fmt.Errorf("cron %s fires at %s, %s after cron %s, which is less than the min interval of %s",
+				names[earliest], t.Format(time.RFC3339), t.Sub(last), lastName, minInterval)
+		}
+		last, lastName = t, names[earliest]
+		next[earliest] = scheds[earliest].Next(t)
+	}
+}
+
+// normalizeSchedule collapses the whitespace of schedule, so that schedules
+// are compared by their fields.
+func normalizeSchedule(schedule string) string {
+	return strings.Join(strings.Fields(schedule), " ")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
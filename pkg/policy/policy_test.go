@@ -0,0 +1,74 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package policy
+
+import (
+	"testing"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller/v1"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+	policies := []v1.CronHPAPolicy{{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "limits"},
+		Spec: v1.CronHPAPolicySpec{
+			MaxReplicas:        int32Ptr(100),
+			MinReplicas:        int32Ptr(1),
+			MinInterval:        &metav1.Duration{Duration: time.Hour},
+			AllowedSchedules:   []string{"0 8 * * *", "0 20 * * *", "30 8 * * *"},
+			AllowedTargetKinds: []string{"Deployment"},
+			MaxCrons:           int32Ptr(2),
+		},
+	}}
+	newCronHPA := func(kind string, crons ...v1.Cron) *v1.CronHPA {
+		return &v1.CronHPA{Spec: v1.CronHPASpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{Kind: kind, Name: "web"},
+			Profiles:       map[string]v1.Profile{"huge": {Replicas: 500}},
+			Crons:          crons,
+		}}
+	}
+	morning := v1.Cron{Name: "morning", Schedule: "0  8 * * *", TargetReplicas: 10}
+	evening := v1.Cron{Name: "evening", Schedule: "0 20 * * *", TargetReplicas: 2}
+
+	tests := []struct {
+		name    string
+		cronhpa *v1.CronHPA
+		valid   bool
+	}{
+		{"valid", newCronHPA("Deployment", morning, evening), true},
+		{"target kind", newCronHPA("StatefulSet", morning, evening), false},
+		{"too many crons", newCronHPA("Deployment", morning, evening, v1.Cron{Name: "night", Schedule: "0 20 * * *", TargetReplicas: 1}), false},
+		{"schedule", newCronHPA("Deployment", v1.Cron{Name: "minutely", Schedule: "* * * * *", TargetReplicas: 1}), false},
+		{"max replicas", newCronHPA("Deployment", v1.Cron{Name: "morning", Schedule: "0 8 * * *", TargetReplicas: 500}), false},
+		{"max replicas by profile", newCronHPA("Deployment", v1.Cron{Name: "morning", Schedule: "0 8 * * *", Profile: "huge"}), false},
+		{"min replicas", newCronHPA("Deployment", v1.Cron{Name: "morning", Schedule: "0 8 * * *"}), false},
+		{"min interval", newCronHPA("Deployment", morning, v1.Cron{Name: "late", Schedule: "30 8 * * *", TargetReplicas: 2}), false},
+	}
+	now := time.Date(2026, 10, 19, 0, 0, 0, 0, time.Local)
+	for _, test := range tests {
+		err := Validate(test.cronhpa, policies, now)
+		if valid := err == nil; valid != test.valid {
+			t.Errorf("%s: expected valid %v, got %v", test.name, test.valid, err)
+		}
+	}
+}
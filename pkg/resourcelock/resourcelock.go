@@ -0,0 +1,72 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package resourcelock extends client-go's leader election locks with
+// coordination.k8s.io Leases.
+package resourcelock
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/kubernetes"
+	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeasesResourceLock is the lock type of LeaseLock.
+const LeasesResourceLock = "leases"
+
+// LockTypes are all supported lock types.
+var LockTypes = []string{rl.EndpointsResourceLock, rl.ConfigMapsResourceLock, LeasesResourceLock}
+
+// ValidateLockType returns an error unless lockType is one of LockTypes.
+func ValidateLockType(lockType string) error {
+	for _, t := range LockTypes {
+		if t == lockType {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown lock type %q, which must be one of %s", lockType, strings.Join(LockTypes, ", "))
+}
+
+// New creates a lock of lockType, which is one of LockTypes.
+func New(lockType string, namespace string, name string, client kubernetes.Interface, rlc rl.ResourceLockConfig) (rl.Interface, error) {
+	if lockType == LeasesResourceLock {
+		return &LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+			Client:     client.CoordinationV1(),
+			LockConfig: rlc,
+		}, nil
+	}
+	return rl.New(lockType, namespace, name, client.CoreV1(), rlc)
+}
+
+// NewIdentity returns a unique identity of this process, which is the hostname
+// with a random suffix, so that processes sharing a hostname don't collide.
+func NewIdentity() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	return hostname + "_" + utilrand.String(8), nil
+}
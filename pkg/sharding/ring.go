@@ -0,0 +1,74 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package sharding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is the number of points each member has on the ring, which
+// evens out the distribution of keys.
+const virtualNodes = 100
+
+// Ring is a consistent hash ring of members. When a member joins or leaves,
+// only keys of that member move.
+type Ring struct {
+	hashes  []uint32
+	members map[uint32]string
+}
+
+// NewRing returns a ring of members.
+func NewRing(members []string) *Ring {
+	r := &Ring{members: map[uint32]string{}}
+	for _, member := range members {
+		for i := 0; i < virtualNodes; i++ {
+			h := hash(member + "#" + strconv.Itoa(i))
+			// On collision, keep the smaller member so that all replicas agree.
+			if m, ok := r.members[h]; ok && m < member {
+				continue
+			} else if !ok {
+				r.hashes = append(r.hashes, h)
+			}
+			r.members[h] = member
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+	return r
+}
+
+// Owner returns the member owning key, or "" if the ring is empty.
+func (r *Ring) Owner(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.members[r.hashes[i]]
+}
+
+// hash spreads similar strings, e.g. names with a numeric suffix, evenly over the ring.
+func hash(s string) uint32 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint32(sum[:4])
+}
@@ -0,0 +1,52 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package sharding
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRingOwner(t *testing.T) {
+	if owner := NewRing(nil).Owner("default/foo"); owner != "" {
+		t.Errorf("expected no owner on empty ring, got %q", owner)
+	}
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("default/cronhpa-%d", i)
+	}
+	ring := NewRing([]string{"a", "b", "c"})
+	counts := map[string]int{}
+	for _, key := range keys {
+		counts[ring.Owner(key)]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		if counts[member] < 200 {
+			t.Errorf("expected member %s to own a fair share of keys, got %d of %d", member, counts[member], len(keys))
+		}
+	}
+
+	// Only keys of the leaving member move.
+	shrunk := NewRing([]string{"a", "c"})
+	for _, key := range keys {
+		if before, after := ring.Owner(key), shrunk.Owner(key); before != "b" && before != after {
+			t.Errorf("expected key %s to stay on %s, moved to %s", key, before, after)
+		}
+	}
+}
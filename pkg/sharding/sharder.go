@@ -0,0 +1,303 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+// Package sharding splits CronHPAs among active controller replicas.
+//
+// Each replica renews a membership Lease of its group. Live members form a
+// consistent hash ring, and each CronHPA is owned by one member of the ring.
+// When membership changes, a replica waits a grace period before acting on
+// keys it gained, by when the previous owner has observed the change and
+// stopped acting on them.
+package sharding
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tkestack.io/cron-hpa/pkg/metrics"
+	"tkestack.io/cron-hpa/pkg/resourcelock"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationclientv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	rl "k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+)
+
+// GroupLabel is the label of membership leases, whose value is the group name.
+const GroupLabel = "extensions.tkestack.io/cron-hpa-shard-group"
+
+// staleLeaseDurations is how many LeaseDurations a lease may go unrenewed
+// before it is deleted, e.g. after its replica crashed without leaving.
+const staleLeaseDurations = 3
+
+// Config is the configuration of a Sharder.
+type Config struct {
+	// Namespace and Group identify the membership leases.
+	Namespace string
+	Group     string
+	// Identity is the unique identity of this replica.
+	Identity string
+	// LeaseDuration is how long a member is considered alive after its last
+	// observed renewal. It is also the grace period before acting on gained keys.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long this replica keeps acting on its keys while it
+	// fails to renew its lease. It must be less than LeaseDuration.
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval of renewing the lease and refreshing members.
+	RetryPeriod time.Duration
+}
+
+// observation is the last change of a member's lease observed by this replica.
+// Liveness is judged by the local clock, so that clock skew among replicas
+// doesn't matter.
+type observation struct {
+	renewTime    metav1.MicroTime
+	observedTime time.Time
+}
+
+// Sharder maintains the membership of this replica and decides which keys it owns.
+type Sharder struct {
+	config Config
+	client coordinationclientv1.LeasesGetter
+
+	lock sync.Mutex
+	// lease is the membership lease of this replica.
+	lease *coordinationv1.Lease
+	// lastRenewTime is when this replica last renewed its lease.
+	lastRenewTime time.Time
+	observations  map[string]observation
+	ring          *Ring
+	// ownedSince is when this replica started to own each key.
+	ownedSince map[string]time.Time
+}
+
+// NewSharder returns a Sharder which has no members until Run.
+func NewSharder(client coordinationclientv1.LeasesGetter, config Config) (*Sharder, error) {
+	if config.LeaseDuration <= config.RenewDeadline {
+		return nil, fmt.Errorf("leaseDuration must be greater than renewDeadline")
+	}
+	if config.RenewDeadline <= config.RetryPeriod {
+		return nil, fmt.Errorf("renewDeadline must be greater than retryPeriod")
+	}
+	return &Sharder{
+		config:       config,
+		client:       client,
+		observations: map[string]observation{},
+		ring:         NewRing(nil),
+		ownedSince:   map[string]time.Time{},
+	}, nil
+}
+
+// Run renews the membership lease and refreshes members until stopCh is closed,
+// and then deletes the lease so that other members take over.
+func (s *Sharder) Run(stopCh <-chan struct{}) {
+	klog.Infof("Joining shard group %s/%s as %s", s.config.Namespace, s.config.Group, s.config.Identity)
+	wait.Until(s.refresh, s.config.RetryPeriod, stopCh)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ring = NewRing(nil)
+	s.ownedSince = map[string]time.Time{}
+	err := s.client.Leases(s.config.Namespace).Delete(s.leaseName(), &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		klog.Errorf("Failed to delete membership lease %s: %v", s.leaseName(), err)
+		return
+	}
+	klog.Infof("Left shard group %s/%s", s.config.Namespace, s.config.Group)
+}
+
+// Owned returns keys which this replica acts on, i.e. keys it has owned for at
+// least the grace period. It should be called with all keys on every sync.
+func (s *Sharder) Owned(keys []string) sets.String {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	owned := sets.NewString()
+	ownedSince := map[string]time.Time{}
+	if now.Sub(s.lastRenewTime) > s.config.RenewDeadline {
+		// Other members may have dropped this replica already.
+		s.ownedSince = ownedSince
+		return owned
+	}
+	for _, key := range keys {
+		if s.ring.Owner(key) != s.config.Identity {
+			continue
+		}
+		since, ok := s.ownedSince[key]
+		if !ok {
+			since = now
+		}
+		ownedSince[key] = since
+		if now.Sub(since) >= s.config.LeaseDuration {
+			owned.Insert(key)
+		}
+	}
+	s.ownedSince = ownedSince
+	return owned
+}
+
+// Check returns an error if this replica failed to renew its membership lease recently.
+func (s *Sharder) Check() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.lastRenewTime.IsZero() {
+		return nil
+	}
+	if since := time.Since(s.lastRenewTime); since > s.config.RenewDeadline {
+		return fmt.Errorf("membership lease not renewed in the last %v", since)
+	}
+	return nil
+}
+
+// refresh renews the membership lease, rebuilds the ring from live members,
+// and deletes stale leases.
+func (s *Sharder) refresh() {
+	renewed := s.renew()
+
+	leases, err := s.client.Leases(s.config.Namespace).List(metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(labels.Set{GroupLabel: s.config.Group}).String(),
+	})
+	if err != nil {
+		klog.Errorf("Failed to list membership leases: %v", err)
+		return
+	}
+	for _, lease := range s.observe(leases.Items, renewed) {
+		klog.Infof("Deleting stale membership lease %s", lease.Name)
+		uid := lease.UID
+		err := s.client.Leases(s.config.Namespace).Delete(lease.Name, &metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+		if err != nil && !errors.IsNotFound(err) {
+			klog.Errorf("Failed to delete stale membership lease %s: %v", lease.Name, err)
+		}
+	}
+}
+
+// observe rebuilds the ring from leases, and returns leases of other members
+// which have not been renewed for staleLeaseDurations.
+func (s *Sharder) observe(leases []coordinationv1.Lease, renewed bool) []coordinationv1.Lease {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := time.Now()
+	if renewed {
+		s.lastRenewTime = now
+	}
+	observations := map[string]observation{}
+	var stale []coordinationv1.Lease
+	for i := range leases {
+		record := resourcelock.LeaseSpecToLeaderElectionRecord(&leases[i].Spec)
+		if record.HolderIdentity == "" || leases[i].Spec.RenewTime == nil {
+			continue
+		}
+		o, ok := s.observations[record.HolderIdentity]
+		if !ok || !o.renewTime.Equal(leases[i].Spec.RenewTime) {
+			o = observation{renewTime: *leases[i].Spec.RenewTime, observedTime: now}
+		}
+		if record.HolderIdentity != s.config.Identity && now.Sub(o.observedTime) > staleLeaseDurations*s.config.LeaseDuration {
+			stale = append(stale, leases[i])
+			continue
+		}
+		observations[record.HolderIdentity] = o
+	}
+	s.observations = observations
+
+	var members []string
+	for identity, o := range observations {
+		if identity == s.config.Identity || now.Sub(o.observedTime) <= s.config.LeaseDuration {
+			members = append(members, identity)
+		}
+	}
+	sort.Strings(members)
+	if !sets.NewString(members...).Equal(s.memberSet()) {
+		klog.Infof("Members of shard group %s/%s: %v", s.config.Namespace, s.config.Group, members)
+	}
+	s.ring = NewRing(members)
+	metrics.ShardMembers.Set(float64(len(members)))
+	return stale
+}
+
+// renew creates or renews the membership lease of this replica.
+func (s *Sharder) renew() bool {
+	now := metav1.NowMicro()
+	spec := resourcelock.LeaderElectionRecordToLeaseSpec(&rl.LeaderElectionRecord{
+		HolderIdentity:       s.config.Identity,
+		LeaseDurationSeconds: int(s.config.LeaseDuration / time.Second),
+	})
+	spec.AcquireTime = nil
+	spec.RenewTime = &now
+	spec.LeaseTransitions = nil
+
+	leases := s.client.Leases(s.config.Namespace)
+	var err error
+	if s.lease == nil {
+		s.lease, err = leases.Get(s.leaseName(), metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			s.lease, err = leases.Create(&coordinationv1.Lease{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      s.leaseName(),
+					Namespace: s.config.Namespace,
+					Labels:    map[string]string{GroupLabel: s.config.Group},
+				},
+				Spec: spec,
+			})
+			if err != nil {
+				s.lease = nil
+				klog.Errorf("Failed to create membership lease %s: %v", s.leaseName(), err)
+				return false
+			}
+			return true
+		}
+		if err != nil {
+			s.lease = nil
+			klog.Errorf("Failed to get membership lease %s: %v", s.leaseName(), err)
+			return false
+		}
+	}
+	lease := s.lease.DeepCopy()
+	lease.Spec = spec
+	s.lease, err = leases.Update(lease)
+	if err != nil {
+		// Get the latest lease next time.
+		s.lease = nil
+		klog.Errorf("Failed to renew membership lease %s: %v", s.leaseName(), err)
+		return false
+	}
+	return true
+}
+
+func (s *Sharder) memberSet() sets.String {
+	members := sets.NewString()
+	for _, member := range s.ring.members {
+		members.Insert(member)
+	}
+	return members
+}
+
+// leaseName returns the name of the membership lease of this replica. Lease
+// names can't contain underscores, which identities may contain.
+func (s *Sharder) leaseName() string {
+	return s.config.Group + "-" + strings.Replace(strings.ToLower(s.config.Identity), "_", "-", -1)
+}
@@ -0,0 +1,94 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package sharding
+
+import (
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newMemberLease(name, identity string) *coordinationv1.Lease {
+	renewTime := metav1.NowMicro()
+	return &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "kube-system",
+			Labels:    map[string]string{GroupLabel: "cron-hpa"},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity: &identity,
+			RenewTime:      &renewTime,
+		},
+	}
+}
+
+func TestRefreshDeletesStaleLeases(t *testing.T) {
+	client := fake.NewSimpleClientset(newMemberLease("cron-hpa-b", "b"), newMemberLease("cron-hpa-c", "c"))
+	sharder, err := NewSharder(client.CoordinationV1(), Config{
+		Namespace:     "kube-system",
+		Group:         "cron-hpa",
+		Identity:      "a",
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 10 * time.Second,
+		RetryPeriod:   2 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharder.refresh()
+	if members := sharder.memberSet(); !members.HasAll("a", "b", "c") || members.Len() != 3 {
+		t.Fatalf("expected members a, b and c, got %v", members.List())
+	}
+
+	// b keeps renewing, while c has crashed long ago
+	lease, err := client.CoordinationV1().Leases("kube-system").Get("cron-hpa-b", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renewTime := metav1.NewMicroTime(lease.Spec.RenewTime.Add(time.Second))
+	lease.Spec.RenewTime = &renewTime
+	if _, err := client.CoordinationV1().Leases("kube-system").Update(lease); err != nil {
+		t.Fatal(err)
+	}
+	for _, identity := range []string{"b", "c"} {
+		o := sharder.observations[identity]
+		o.observedTime = time.Now().Add(-staleLeaseDurations * sharder.config.LeaseDuration * 2)
+		sharder.observations[identity] = o
+	}
+	sharder.refresh()
+
+	if members := sharder.memberSet(); !members.HasAll("a", "b") || members.Len() != 2 {
+		t.Errorf("expected members a and b, got %v", members.List())
+	}
+	leases, err := client.CoordinationV1().Leases("kube-system").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := sets.NewString()
+	for _, lease := range leases.Items {
+		names.Insert(lease.Name)
+	}
+	if !names.Equal(sets.NewString("cron-hpa-a", "cron-hpa-b")) {
+		t.Errorf("expected leases cron-hpa-a and cron-hpa-b, got %v", names.List())
+	}
+}
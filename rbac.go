@@ -0,0 +1,309 @@
+/*
+ * Tencent is pleased to support the open source community by making TKEStack available.
+ *
+ * Copyright (C) 2012-2019 Tencent. All Rights Reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License"); you may not use
+ * this file except in compliance with the License. You may obtain a copy of the
+ * License at
+ *
+ * https://opensource.org/licenses/Apache-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+ * WARRANTIES OF ANY KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"tkestack.io/cron-hpa/pkg/apis/cronhpacontroller"
+	"tkestack.io/cron-hpa/pkg/resourcelock"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// rbacName is the name of the service account, roles and bindings of the controller.
+const rbacName = "cron-hpa-controller"
+
+// scopedRules are needed in every watched namespace, or cluster wide.
+func scopedRules() []rbacv1.PolicyRule {
+	cronhpaVerbs := []string{"get", "list", "watch", "update"}
+	if apiAddress != "" {
+		// The API sets annotations of cronhpas
+		cronhpaVerbs = append(cronhpaVerbs, "patch")
+	}
+	return []rbacv1.PolicyRule{
+		{
+			APIGroups: []string{cronhpacontroller.GroupName},
+			Resources: []string{"cronhpas"},
+			Verbs:     cronhpaVerbs,
+		},
+		{
+			APIGroups: []string{cronhpacontroller.GroupName},
+			Resources: []string{"cronhpaexecutions"},
+			Verbs:     []string{"list", "create", "delete"},
+		},
+		{
+			APIGroups: []string{cronhpacontroller.GroupName},
+			Resources: []string{"cronhpapolicies"},
+			Verbs:     []string{"list"},
+		},
+		{
+			// HPAs are indexed by their scale targets to find conflicts
+			APIGroups: []string{"autoscaling"},
+			Resources: []string{"horizontalpodautoscalers"},
+			Verbs:     []string{"list", "watch"},
+		},
+		{
+			APIGroups: []string{"*"},
+			Resources: []string{"*/scale"},
+			Verbs:     []string{"get", "update"},
+		},
+		{
+			// Pod templates of scale targets are read by quotaPolicy and respectPDB
+			APIGroups: []string{"", "apps", "extensions"},
+			Resources: []string{"replicationcontrollers", "deployments", "replicasets", "statefulsets"},
+			Verbs:     []string{"get"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"resourcequotas"},
+			Verbs:     []string{"list"},
+		},
+		{
+			// Pod deletion costs are set by podDeletionCost
+			APIGroups: []string{""},
+			Resources: []string{"pods"},
+			Verbs:     []string{"list", "patch"},
+		},
+		{
+			// Job hooks of preScale and postScale
+			APIGroups: []string{"batch"},
+			Resources: []string{"jobs"},
+			Verbs:     []string{"get", "create", "delete"},
+		},
+		{
+			APIGroups: []string{"policy"},
+			Resources: []string{"poddisruptionbudgets"},
+			Verbs:     []string{"list"},
+		},
+		{
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+			Verbs:     []string{"create", "patch"},
+		},
+	}
+}
+
+// clusterRules are needed cluster wide regardless of watched namespaces.
+func clusterRules() []rbacv1.PolicyRule {
+	var rules []rbacv1.PolicyRule
+	if len(watchNamespaces) == 0 {
+		// ScalingFreezes are cluster-scoped, and only watched with all namespaces
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{cronhpacontroller.GroupName},
+			Resources: []string{"scalingfreezes"},
+			Verbs:     []string{"list", "watch"},
+		})
+	}
+	if createCRD {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"apiextensions.k8s.io"},
+			Resources: []string{"customresourcedefinitions"},
+			Verbs:     []string{"get", "create", "update"},
+		})
+	}
+	if registerAdmission {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"admissionregistration.k8s.io"},
+			Resources: []string{"validatingwebhookconfigurations", "mutatingwebhookconfigurations"},
+			Verbs:     []string{"get", "create", "update"},
+		})
+	}
+	if apiAddress != "" && apiTokenReview {
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"authentication.k8s.io"},
+			Resources: []string{"tokenreviews"},
+			Verbs:     []string{"create"},
+		})
+	}
+	if registerAdmission || (apiAddress != "" && apiTokenReview) {
+		// The admission webhook reviews whether authors of cronhpas may scale
+		// their targets, and the API whether token users may patch cronhpas
+		rules = append(rules, rbacv1.PolicyRule{
+			APIGroups: []string{"authorization.k8s.io"},
+			Resources: []string{"subjectaccessreviews"},
+			Verbs:     []string{"create"},
+		})
+	}
+	return rules
+}
+
+// splitAPITokenSecret returns the namespace and name of --api-token-secret.
+func splitAPITokenSecret() (string, string, error) {
+	parts := strings.Split(apiTokenSecret, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid --api-token-secret %q, expected namespace/name", apiTokenSecret)
+	}
+	return parts[0], parts[1], nil
+}
+
+// lockRules are needed in the namespace of the leader lock or membership leases.
+func lockRules() []rbacv1.PolicyRule {
+	switch {
+	case enableSharding:
+		return []rbacv1.PolicyRule{{
+			APIGroups: []string{"coordination.k8s.io"},
+			Resources: []string{"leases"},
+			Verbs:     []string{"get", "list", "create", "update", "delete"},
+		}}
+	case leaderElection.LeaderElect:
+		rule := rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{leaderElection.ResourceLock},
+			Verbs:     []string{"get", "create", "update"},
+		}
+		if leaderElection.ResourceLock == resourcelock.LeasesResourceLock {
+			rule.APIGroups = []string{"coordination.k8s.io"}
+		}
+		return []rbacv1.PolicyRule{rule, {
+			APIGroups: []string{""},
+			Resources: []string{"events"},
+			Verbs:     []string{"create", "patch"},
+		}}
+	}
+	return nil
+}
+
+// rbacObjects returns the roles and bindings needed with the flags. Without
+// --watch-namespaces they are cluster wide, otherwise namespaced roles are
+// bound in each watched namespace.
+func rbacObjects() ([]runtime.Object, error) {
+	if err := resourcelock.ValidateLockType(leaderElection.ResourceLock); err != nil {
+		return nil, err
+	}
+
+	subjects := []rbacv1.Subject{{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      rbacName,
+		Namespace: namespace,
+	}}
+	objects := []runtime.Object{&corev1.ServiceAccount{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ServiceAccount"},
+		ObjectMeta: metav1.ObjectMeta{Name: rbacName, Namespace: namespace},
+	}}
+	addRole := func(ns string, rules []rbacv1.PolicyRule) {
+		objects = append(objects, &rbacv1.Role{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "Role"},
+			ObjectMeta: metav1.ObjectMeta{Name: rbacName, Namespace: ns},
+			Rules:      rules,
+		}, &rbacv1.RoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: rbacName, Namespace: ns},
+			Subjects:   subjects,
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: rbacName},
+		})
+	}
+
+	clusterRoleRules := clusterRules()
+	roleRules := map[string][]rbacv1.PolicyRule{}
+	var roleNamespaces []string
+	if len(watchNamespaces) == 0 {
+		clusterRoleRules = append(scopedRules(), clusterRoleRules...)
+	} else {
+		for _, ns := range watchNamespaces {
+			if _, ok := roleRules[ns]; !ok {
+				roleNamespaces = append(roleNamespaces, ns)
+			}
+			roleRules[ns] = scopedRules()
+		}
+	}
+	addRoleRules := func(ns string, rules ...rbacv1.PolicyRule) {
+		if _, ok := roleRules[ns]; !ok {
+			roleNamespaces = append(roleNamespaces, ns)
+		}
+		roleRules[ns] = append(roleRules[ns], rules...)
+	}
+	if rules := lockRules(); len(rules) > 0 {
+		lockNamespace := leaderElectResourceNamespace
+		if lockNamespace == "" {
+			lockNamespace = namespace
+		}
+		addRoleRules(lockNamespace, rules...)
+	}
+	if len(watchNamespaces) > 0 {
+		// Default CronHPAPolicies
+		addRoleRules(namespace, rbacv1.PolicyRule{
+			APIGroups: []string{cronhpacontroller.GroupName},
+			Resources: []string{"cronhpapolicies"},
+			Verbs:     []string{"list"},
+		})
+	}
+	if pauseConfigMap != "" {
+		addRoleRules(namespace, rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps"},
+			Verbs:     []string{"list", "watch"},
+		})
+	}
+	if apiAddress != "" && apiTokenSecret != "" {
+		secretNamespace, secretName, err := splitAPITokenSecret()
+		if err != nil {
+			return nil, err
+		}
+		addRoleRules(secretNamespace, rbacv1.PolicyRule{
+			APIGroups:     []string{""},
+			Resources:     []string{"secrets"},
+			ResourceNames: []string{secretName},
+			Verbs:         []string{"get"},
+		})
+	}
+
+	if len(clusterRoleRules) > 0 {
+		objects = append(objects, &rbacv1.ClusterRole{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole"},
+			ObjectMeta: metav1.ObjectMeta{Name: rbacName},
+			Rules:      clusterRoleRules,
+		}, &rbacv1.ClusterRoleBinding{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding"},
+			ObjectMeta: metav1.ObjectMeta{Name: rbacName},
+			Subjects:   subjects,
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: rbacName},
+		})
+	}
+	for _, ns := range roleNamespaces {
+		addRole(ns, roleRules[ns])
+	}
+	return objects, nil
+}
+
+// writeRBAC writes rbacObjects to w as a multi-document YAML.
+func writeRBAC(w io.Writer) error {
+	objects, err := rbacObjects()
+	if err != nil {
+		return err
+	}
+	for i, object := range objects {
+		data, err := yaml.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			data = append([]byte("---\n"), data...)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}